@@ -1,17 +1,55 @@
 package appKernel
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/authz"
+	"github.com/waponix/netgo/cors"
+	"github.com/waponix/netgo/csrf"
+	"github.com/waponix/netgo/jwt"
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/ratelimit"
 	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/session"
 	"github.com/waponix/netgo/src/product"
+	"github.com/waponix/netgo/view"
 )
 
 type Kernel struct {
+	Log      *logger.Log
+	View     *view.Engine
+	Sessions *session.Manager
 }
 
 func New() *Kernel {
-	return &Kernel{}
+	log := logger.New()
+	log.Filename = logger.STDOUT
+
+	// templates are parsed again on every render when developing, APP_ENV=development
+	engine := view.New(view.DEFAULT_DIR, view.Options{
+		Layout:      "layouts/main",
+		Development: os.Getenv("APP_ENV") == "development",
+		Assets:      os.DirFS("public"),
+	})
+	view.SetInstance(engine)
+
+	return &Kernel{
+		Log:  log,
+		View: engine,
+		Sessions: session.NewManager(session.Options{
+			// browsers only send secure cookies over https, plain http is only allowed when developing
+			Insecure: os.Getenv("APP_ENV") == "development",
+			Log:      log,
+		}),
+	}
 }
 
 func TestResponder() {
@@ -23,11 +61,100 @@ func TestMiddleware() bool {
 }
 
 func (_kernel Kernel) Init() {
+	// stops the session collector once the server is done
+	defer _kernel.Sessions.Close()
+
+	strategies := []auth.Strategy{auth.Session(nil)}
+
+	// bearer tokens are accepted once the keys to verify them are provided, e.g. JWT_JWKS_FILE=config/jwks.json
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		keys, err := jwt.LoadJWKS(path)
+		if err != nil {
+			_kernel.Log.Fatal(err.Error())
+			return
+		}
+
+		strategies = append(strategies, auth.JWT(jwt.NewVerifier(keys, jwt.Options{
+			Issuer:   os.Getenv("JWT_ISSUER"),
+			Audience: strings.Fields(os.Getenv("JWT_AUDIENCE")),
+		})))
+	}
+
+	authz.SetInstance(authz.New(authz.Options{
+		Permissions: map[string][]string{
+			"customer": {"product:read"},
+			"admin":    {authz.ALL},
+		},
+	}))
+
 	router.Instance().
-		RegisterGroup(
-			"/api",
-			router.Get("/product/{productId}", product.GetProductHandler),
+		Use(
+			logger.RequestLogger(_kernel.Log, logger.RequestLoggerOptions{UserID: auth.UserID}),
+			logger.AccessLog(_kernel.Log, logger.AccessLogOptions{Format: logger.ACCESS_COMBINED, UserID: auth.UserID}),
+			_kernel.Sessions.Middleware,
+			// authenticates first so that the CSRF check knows which strategy identified the user
+			auth.Authenticate(strategies...),
+			csrf.New(csrf.Options{
+				Mode: csrf.MODE_SYNCHRONIZER,
+				// the frontends allowed to call the api may also post to it
+				TrustedOrigins: strings.Fields(os.Getenv("CORS_ALLOWED_ORIGINS")),
+				// browsers never add a bearer token on their own, such requests can not be forged
+				Exempt: authenticatedByJWT,
+			}),
+		).
+		Register(
+			router.Static(view.DEFAULT_ASSET_PREFIX, "public"),
+		).
+		RegisterGroups(
+			router.Group(
+				"/api",
+				router.Get("/product/{productId}", product.GetProductHandler, authz.Can("product:read")).SetName("product.show"),
+			).Use(cors.New(cors.Options{
+				// e.g. CORS_ALLOWED_ORIGINS="https://app.example.com https://*.example.com"
+				AllowedOrigins: strings.Fields(os.Getenv("CORS_ALLOWED_ORIGINS")),
+				// lets the frontend read the CSRF token and send it back
+				AllowedHeaders: append(cors.DefaultHeaders(), csrf.DEFAULT_HEADER),
+				ExposedHeaders: []string{csrf.DEFAULT_HEADER},
+				MaxAge:         time.Hour,
+			}), ratelimit.New(ratelimit.Options{
+				Limit:  100,
+				Window: time.Minute,
+				Key:    ratelimit.FirstOf(ratelimit.ByUser, ratelimit.ByIP(false)),
+			})),
 		)
 
-	http.ListenAndServe(":8080", router.Instance().Mux())
+	if err := router.Instance().Validate(); err != nil {
+		_kernel.Log.Fatal(err.Error())
+		return
+	}
+
+	if os.Getenv("APP_ENV") != "production" {
+		for _, requirement := range authz.Requirements(router.Instance().OrderedRoutes()...) {
+			if len(requirement.Permissions) > 0 {
+				_kernel.Log.Debug(requirement.Method + " " + requirement.Path + " requires " + strings.Join(requirement.Permissions, ", "))
+			}
+		}
+	}
+
+	server := &http.Server{Addr: ":8080", Handler: router.Instance().Mux()}
+
+	// ctrl+c or SIGTERM lets the running requests finish before shutting down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_kernel.Log.Error(err.Error())
+	}
+}
+
+// api clients authenticate with a bearer token instead of the session cookie. Only a token that was
+// verified counts, a made up one next to the session cookie of the user is no reason to skip the check
+func authenticatedByJWT(r *http.Request) bool {
+	user := auth.FromRequest(r)
+	return user != nil && user.Strategy == auth.STRATEGY_JWT
 }
@@ -0,0 +1,146 @@
+// Package auth identifies the user behind a request. Authenticate() tries the strategies in order and
+// attaches the first user found to the request, RequireAuth() refuses requests without one.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// the authenticated user
+type User struct {
+	ID    string
+	Name  string
+	Roles []string
+	// anything else known about the user, like the claims of a JWT
+	Attributes map[string]any
+	// name of the strategy that authenticated the user
+	Strategy string
+}
+
+// a way of identifying users
+type Strategy interface {
+	Name() string
+	// returns the user, nil without an error when the request carries no credentials the strategy
+	// knows about and ErrInvalidCredentials when it carries wrong ones
+	Authenticate(r *http.Request) (*User, error)
+	// the WWW-Authenticate challenge sent along with 401 responses, empty for none
+	Challenge() string
+}
+
+type contextKey int
+
+const (
+	stateKey contextKey = iota
+)
+
+// what the authentication middleware found out about the request
+type state struct {
+	user       *User
+	challenges []string
+}
+
+// Public: middleware attaching the user identified by the first successful strategy to the request,
+// requests without (valid) credentials go through anonymously, use RequireAuth() to refuse them
+func Authenticate(strategies ...Strategy) func(http.Handler) http.Handler {
+	var challenges []string
+	for _, strategy := range strategies {
+		if challenge := strategy.Challenge(); challenge != "" {
+			challenges = append(challenges, challenge)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := &state{challenges: challenges}
+
+			for _, strategy := range strategies {
+				user, err := strategy.Authenticate(r)
+				if err != nil {
+					logger.FromRequest(r).Notice("auth: " + strategy.Name() + ": " + err.Error())
+					continue
+				}
+
+				if user != nil {
+					user.Strategy = strategy.Name()
+					s.user = user
+					break
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), stateKey, s))
+			// lets the access log find out who made the request
+			logger.TrackRequest(r)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Public: middleware answering requests without an authenticated user with 401, attach it to the
+// routes or groups that need a user. Authenticate() has to run first
+func RequireAuth() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromRequest(r) == nil {
+				Unauthorized(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Public: answers the request with 401 and the WWW-Authenticate challenges of the strategies
+func Unauthorized(w http.ResponseWriter, r *http.Request) {
+	if s, ok := r.Context().Value(stateKey).(*state); ok {
+		for _, challenge := range s.challenges {
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+	}
+
+	router.Error(w, r, http.StatusUnauthorized)
+}
+
+// Public: returns the authenticated user, nil for anonymous requests
+func FromRequest(r *http.Request) *User {
+	if s, ok := r.Context().Value(stateKey).(*state); ok {
+		return s.user
+	}
+
+	return nil
+}
+
+// Public: returns the id of the authenticated user, empty for anonymous requests.
+// Meant for logger.RequestLoggerOptions.UserID
+func UserID(r *http.Request) string {
+	if user := FromRequest(r); user != nil {
+		return user.ID
+	}
+
+	return ""
+}
+
+// Public: returns a copy of the request authenticated as the user, for tests and for handlers
+// that log a user in and keep on serving the request
+func WithUser(r *http.Request, user *User) *http.Request {
+	s := &state{user: user}
+	if current, ok := r.Context().Value(stateKey).(*state); ok {
+		s.challenges = current.challenges
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), stateKey, s))
+}
+
+// reports whether the user has the role
+func (u *User) HasRole(role string) bool {
+	return sliceUtil.Contains(u.Roles, role)
+}
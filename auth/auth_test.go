@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/waponix/netgo/session"
+)
+
+type fakeVerifier map[string]map[string]any
+
+func (f fakeVerifier) Verify(token string) (map[string]any, error) {
+	claims, ok := f[token]
+	if !ok {
+		return nil, errors.New("bad signature")
+	}
+	return claims, nil
+}
+
+func strategies() []Strategy {
+	return []Strategy{
+		Basic("netgo", func(username string, password string) (*User, error) {
+			if username == "admin" && SecureCompare(password, "secret") {
+				return &User{ID: "1", Name: "admin"}, nil
+			}
+			return nil, ErrInvalidCredentials
+		}),
+		APIKey("", func(key string) (*User, error) {
+			if SecureCompare(key, "key-123") {
+				return &User{ID: "2"}, nil
+			}
+			return nil, nil
+		}),
+		JWT(fakeVerifier{
+			"a.b.c": {"sub": "3", "name": "jwt user", "roles": []any{"editor"}},
+			"d.e.f": {"name": "no subject"},
+		}),
+	}
+}
+
+func serve(method string, headers map[string]string, protected bool) (*httptest.ResponseRecorder, *User) {
+	var user *User
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = FromRequest(r)
+	})
+
+	if protected {
+		handler = RequireAuth()(handler)
+	}
+
+	req := httptest.NewRequest(method, "/", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	Authenticate(strategies()...)(handler).ServeHTTP(rec, req)
+
+	return rec, user
+}
+
+func TestStrategiesShouldIdentifyUsers(t *testing.T) {
+	basic := httptest.NewRequest(http.MethodGet, "/", nil)
+	basic.SetBasicAuth("admin", "secret")
+	wrongPassword := httptest.NewRequest(http.MethodGet, "/", nil)
+	wrongPassword.SetBasicAuth("admin", "guess")
+
+	cases := []struct {
+		authorization string
+		id            string
+		strategy      string
+	}{
+		{basic.Header.Get("Authorization"), "1", STRATEGY_BASIC},
+		{wrongPassword.Header.Get("Authorization"), "", ""},
+		{"Bearer key-123", "2", STRATEGY_API_KEY},
+		{"bearer a.b.c", "3", STRATEGY_JWT},
+		{"Bearer x.y.z", "", ""},
+		{"Bearer d.e.f", "", ""},
+		{"Bearer unknown-key", "", ""},
+		{"", "", ""},
+	}
+
+	for _, c := range cases {
+		_, user := serve(http.MethodGet, map[string]string{"Authorization": c.authorization}, false)
+
+		id, strategy := "", ""
+		if user != nil {
+			id, strategy = user.ID, user.Strategy
+		}
+
+		if id != c.id || strategy != c.strategy {
+			t.Fatalf(`user of %q = %q by %q, want %q by %q`, c.authorization, id, strategy, c.id, c.strategy)
+		}
+	}
+
+	_, user := serve(http.MethodGet, map[string]string{"Authorization": "Bearer a.b.c"}, false)
+	if user.Name != "jwt user" || !user.HasRole("editor") || user.Attributes["sub"] != "3" {
+		t.Fatalf(`JWT user = %+v, want the claims mapped`, user)
+	}
+}
+
+func TestRequireAuthShouldRefuseAnonymousRequests(t *testing.T) {
+	rec, _ := serve(http.MethodGet, nil, true)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf(`anonymous request = %d, want 401`, rec.Code)
+	}
+
+	challenges := rec.Header().Values("WWW-Authenticate")
+	if len(challenges) != 3 || challenges[0] != `Basic realm="netgo", charset="UTF-8"` || challenges[1] != "Bearer" {
+		t.Fatalf(`WWW-Authenticate = %q, want the challenges of the strategies`, challenges)
+	}
+
+	if rec, user := serve(http.MethodGet, map[string]string{"Authorization": "Bearer key-123"}, true); rec.Code != http.StatusOK || user.ID != "2" {
+		t.Fatalf(`authenticated request = %d, want 200`, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	RequireAuth()(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf(`request without Authenticate() = %d, want 401`, rec.Code)
+	}
+}
+
+func TestSessionStrategyShouldFollowLoginAndLogout(t *testing.T) {
+	sessions := session.NewManager(session.Options{GCInterval: -1})
+	defer sessions.Close()
+
+	var user *User
+	handler := sessions.Middleware(Authenticate(Session(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = FromRequest(r)
+		switch r.URL.Path {
+		case "/login":
+			Login(r, &User{ID: "42"})
+		case "/logout":
+			Logout(r)
+		}
+	})))
+
+	var cookies []*http.Cookie
+	visit := func(path string) *User {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if set := rec.Result().Cookies(); len(set) > 0 {
+			cookies = set
+		}
+
+		return user
+	}
+
+	if visit("/login") != nil {
+		t.Fatalf(`user before logging in = %+v, want nil`, user)
+	}
+
+	if u := visit("/"); u == nil || u.ID != "42" || u.Strategy != STRATEGY_SESSION {
+		t.Fatalf(`user after logging in = %+v, want 42 by session`, u)
+	}
+
+	visit("/logout")
+	if u := visit("/"); u != nil {
+		t.Fatalf(`user after logging out = %+v, want nil`, u)
+	}
+}
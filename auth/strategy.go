@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/waponix/netgo/session"
+)
+
+// strategy name constants, also found in User.Strategy
+const (
+	STRATEGY_BASIC   = "basic"
+	STRATEGY_API_KEY = "apiKey"
+	STRATEGY_SESSION = "session"
+	STRATEGY_JWT     = "jwt"
+)
+
+// session key of the id of the logged in user
+const SESSION_USER_KEY = "_userId"
+
+// returns the user with the credentials, ErrInvalidCredentials when they are wrong
+type BasicVerifier func(username string, password string) (*User, error)
+
+// returns the user owning the key, nil when the key is unknown
+type APIKeyLookup func(key string) (*User, error)
+
+// returns the user with the id, used to load the user of a session
+type UserLoader func(id string) (*User, error)
+
+// checks a token and returns its claims, implemented by the jwt package
+type TokenVerifier interface {
+	Verify(token string) (map[string]any, error)
+}
+
+type basicStrategy struct {
+	realm  string
+	verify BasicVerifier
+}
+
+// Public: HTTP Basic authentication, only use it over https
+func Basic(realm string, verify BasicVerifier) Strategy {
+	return &basicStrategy{realm: realm, verify: verify}
+}
+
+func (s *basicStrategy) Name() string {
+	return STRATEGY_BASIC
+}
+
+func (s *basicStrategy) Authenticate(r *http.Request) (*User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	return s.verify(username, password)
+}
+
+func (s *basicStrategy) Challenge() string {
+	return fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, s.realm)
+}
+
+type apiKeyStrategy struct {
+	header string
+	lookup APIKeyLookup
+}
+
+// Public: API keys sent as "Authorization: Bearer <key>", or in the given header like X-API-Key.
+// Unknown keys are left to the next strategy so that API keys and JWTs can share the header
+func APIKey(header string, lookup APIKeyLookup) Strategy {
+	return &apiKeyStrategy{header: header, lookup: lookup}
+}
+
+func (s *apiKeyStrategy) Name() string {
+	return STRATEGY_API_KEY
+}
+
+func (s *apiKeyStrategy) Authenticate(r *http.Request) (*User, error) {
+	var key string
+	if s.header != "" {
+		key = r.Header.Get(s.header)
+	} else {
+		key = bearerToken(r)
+	}
+
+	if key == "" {
+		return nil, nil
+	}
+
+	return s.lookup(key)
+}
+
+func (s *apiKeyStrategy) Challenge() string {
+	if s.header != "" {
+		return ""
+	}
+
+	return "Bearer"
+}
+
+type sessionStrategy struct {
+	load UserLoader
+}
+
+// Public: users logged in with Login(), requires the session middleware to run first. Without a
+// loader the user only has its id
+func Session(load UserLoader) Strategy {
+	return &sessionStrategy{load: load}
+}
+
+func (s *sessionStrategy) Name() string {
+	return STRATEGY_SESSION
+}
+
+func (s *sessionStrategy) Authenticate(r *http.Request) (*User, error) {
+	sess := session.FromRequest(r)
+	if sess == nil {
+		return nil, nil
+	}
+
+	id := sess.GetString(SESSION_USER_KEY)
+	if id == "" {
+		return nil, nil
+	}
+
+	if s.load == nil {
+		return &User{ID: id}, nil
+	}
+
+	return s.load(id)
+}
+
+func (s *sessionStrategy) Challenge() string {
+	return ""
+}
+
+// Public: logs the user in for the following requests, the session id is rotated so that an id
+// known before the login can not be used to take the session over
+func Login(r *http.Request, user *User) error {
+	sess := session.FromRequest(r)
+	if sess == nil {
+		return fmt.Errorf("auth: logging in requires the session middleware")
+	}
+
+	sess.Rotate()
+	sess.Set(SESSION_USER_KEY, user.ID)
+
+	return nil
+}
+
+// Public: logs the user of the session out
+func Logout(r *http.Request) {
+	if sess := session.FromRequest(r); sess != nil {
+		sess.Destroy()
+	}
+}
+
+type jwtStrategy struct {
+	verifier TokenVerifier
+}
+
+// Public: JWTs sent as "Authorization: Bearer <token>". The user is built from the claims: sub is
+// the id, name the name and roles the roles, every claim is kept in the attributes
+func JWT(verifier TokenVerifier) Strategy {
+	return &jwtStrategy{verifier: verifier}
+}
+
+func (s *jwtStrategy) Name() string {
+	return STRATEGY_JWT
+}
+
+func (s *jwtStrategy) Authenticate(r *http.Request) (*User, error) {
+	token := bearerToken(r)
+	// API keys share the header, only look at what has the shape of a JWT
+	if strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	user := &User{Attributes: claims}
+	user.ID, _ = claims["sub"].(string)
+	user.Name, _ = claims["name"].(string)
+
+	if roles, ok := claims["roles"].([]any); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				user.Roles = append(user.Roles, name)
+			}
+		}
+	}
+
+	if user.ID == "" {
+		return nil, fmt.Errorf("%w: the token has no subject", ErrInvalidCredentials)
+	}
+
+	return user, nil
+}
+
+func (s *jwtStrategy) Challenge() string {
+	return "Bearer"
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// Public: compares secrets in constant time, for BasicVerifier and APIKeyLookup implementations
+func SecureCompare(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,212 @@
+// Package authz decides what an authenticated user may do. Roles grant permissions like
+// "product:read" and inherit the permissions of other roles, policies can restrict a permission
+// further by looking at the resource, like only letting the owner of a product update it.
+package authz
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+)
+
+const (
+	// grants every permission, "product:*" grants every permission on products
+	ALL       = "*"
+	SEPARATOR = ":"
+)
+
+type Options struct {
+	// the permissions granted by each role, e.g. "editor": {"product:read", "product:update"}
+	Permissions map[string][]string
+	// the roles whose permissions each role inherits, e.g. "admin": {"editor"}
+	Inherits map[string][]string
+}
+
+// decides whether the user may use the permission on the resource, the resource is nil when the
+// permission is checked without one
+type Policy func(user *auth.User, resource any) bool
+
+// loads the resource a route acts on, e.g. the product of the productId parameter. Returns nil
+// when it does not exist
+type Loader func(r *http.Request) (any, error)
+
+type Authorizer struct {
+	// the permissions of each role, inherited ones included
+	permissions map[string][]string
+	policies    map[string]Policy
+}
+
+// read by every request, an atomic keeps concurrent first requests and SetInstance() from racing
+var authorizerInstance atomic.Pointer[Authorizer]
+
+// Public: returns the authorizer used by Can() and Authorize(), one granting nothing unless
+// SetInstance() was called
+func Instance() *Authorizer {
+	if a := authorizerInstance.Load(); a != nil {
+		return a
+	}
+
+	authorizerInstance.CompareAndSwap(nil, New(Options{}))
+	return authorizerInstance.Load()
+}
+
+// Public: makes the authorizer the one returned by Instance()
+func SetInstance(a *Authorizer) {
+	authorizerInstance.Store(a)
+}
+
+// Public: creates an authorizer granting the permissions of the options
+func New(options Options) *Authorizer {
+	a := &Authorizer{
+		permissions: make(map[string][]string, len(options.Permissions)),
+		policies:    make(map[string]Policy),
+	}
+
+	roles := make(map[string]bool)
+	for role := range options.Permissions {
+		roles[role] = true
+	}
+	for role := range options.Inherits {
+		roles[role] = true
+	}
+
+	for role := range roles {
+		a.permissions[role] = collect(role, options, map[string]bool{})
+	}
+
+	return a
+}
+
+// the permissions of the role and of the roles it inherits, seen keeps inheritance cycles from looping
+func collect(role string, options Options, seen map[string]bool) []string {
+	if seen[role] {
+		return nil
+	}
+	seen[role] = true
+
+	permissions := append([]string(nil), options.Permissions[role]...)
+	for _, inherited := range options.Inherits[role] {
+		permissions = append(permissions, collect(inherited, options, seen)...)
+	}
+
+	return permissions
+}
+
+// sets the policy of the permission, users granted the permission by their roles also need the
+// policy to agree. Define policies before serving requests
+func (a *Authorizer) Define(permission string, policy Policy) *Authorizer {
+	a.policies[permission] = policy
+	return a
+}
+
+// reports whether the user may use the permission on the resource, pass a nil resource to check
+// the permission alone
+func (a *Authorizer) Allowed(user *auth.User, permission string, resource any) bool {
+	if user == nil || !a.Granted(user.Roles, permission) {
+		return false
+	}
+
+	if policy, ok := a.policies[permission]; ok {
+		return policy(user, resource)
+	}
+
+	return true
+}
+
+// reports whether one of the roles grants the permission, policies are not consulted
+func (a *Authorizer) Granted(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, granted := range a.permissions[role] {
+			if matches(granted, permission) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// "*" matches any permission and "product:*" any permission starting with "product:"
+func matches(granted string, permission string) bool {
+	if granted == ALL || granted == permission {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(granted, SEPARATOR+ALL)
+	return ok && strings.HasPrefix(permission, prefix+SEPARATOR)
+}
+
+// the middleware checking a permission
+type Check struct {
+	permission string
+	load       Loader
+}
+
+// Public: middleware letting through users granted the permission, pass it when creating a route:
+// router.Get("/product/{productId}", handler, authz.Can("product:read")), or add it to a group with
+// With(). The route keeps it so that Requirements() can list it. Anonymous requests are answered with 401, users without the permission with 403. Authenticate()
+// of the auth package has to run first
+func Can(permission string) *Check {
+	return CanWith(permission, nil)
+}
+
+// Public: like Can(), the resource returned by load is handed to the policy of the permission.
+// Requests for a resource that does not exist are answered with 404
+func CanWith(permission string, load Loader) *Check {
+	return &Check{permission: permission, load: load}
+}
+
+func (c *Check) Permission() string {
+	return c.permission
+}
+
+func (c *Check) Wrap(next http.Handler) http.Handler {
+	return router.MiddlewareFunc(c.Allow).Wrap(next)
+}
+
+// runs the check, returns false once the request is answered
+func (c *Check) Allow(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.FromRequest(r)
+	if user == nil {
+		auth.Unauthorized(w, r)
+		return false
+	}
+
+	var resource any
+	if c.load != nil {
+		var err error
+		if resource, err = c.load(r); err != nil {
+			logger.FromRequest(r).Error("authz: loading the resource of " + c.permission + ": " + err.Error())
+			router.Error(w, r, http.StatusInternalServerError)
+			return false
+		}
+
+		if resource == nil {
+			router.Error(w, r, http.StatusNotFound)
+			return false
+		}
+	}
+
+	if !Instance().Allowed(user, c.permission, resource) {
+		logger.FromRequest(r).Notice("authz: refused " + c.permission + " to user " + user.ID)
+		Forbidden(w, r)
+		return false
+	}
+
+	return true
+}
+
+// Public: reports whether the user of the request may use the permission on the resource, for
+// handlers checking a resource they loaded themselves. Answer with Forbidden() when it returns false
+func Authorize(r *http.Request, permission string, resource any) bool {
+	return Instance().Allowed(auth.FromRequest(r), permission, resource)
+}
+
+// Public: answers the request with 403 through router.Error()
+func Forbidden(w http.ResponseWriter, r *http.Request) {
+	router.Error(w, r, http.StatusForbidden)
+}
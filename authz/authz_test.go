@@ -0,0 +1,177 @@
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/router"
+)
+
+type product struct {
+	ID    string
+	Owner string
+}
+
+func authorizer() *Authorizer {
+	return New(Options{
+		Permissions: map[string][]string{
+			"customer": {"product:read"},
+			"seller":   {"product:update"},
+			"editor":   {"product:*"},
+			"admin":    {ALL},
+		},
+		Inherits: map[string][]string{
+			"seller": {"customer"},
+			"editor": {"seller"},
+		},
+	}).Define("product:update", func(user *auth.User, resource any) bool {
+		p, ok := resource.(*product)
+		return user.HasRole("admin") || user.HasRole("editor") || (ok && p.Owner == user.ID)
+	})
+}
+
+func TestAllowedShouldFollowRolesAndPolicies(t *testing.T) {
+	a := authorizer()
+
+	own := &product{ID: "1", Owner: "7"}
+	other := &product{ID: "2", Owner: "8"}
+
+	cases := []struct {
+		roles      []string
+		permission string
+		resource   any
+		want       bool
+	}{
+		{nil, "product:read", nil, false},
+		{[]string{"customer"}, "product:read", nil, true},
+		{[]string{"customer"}, "product:update", own, false},
+		{[]string{"seller"}, "product:read", nil, true},
+		{[]string{"seller"}, "product:update", own, true},
+		{[]string{"seller"}, "product:update", other, false},
+		{[]string{"editor"}, "product:update", other, true},
+		{[]string{"editor"}, "product:delete", nil, true},
+		{[]string{"editor"}, "productivity:read", nil, false},
+		{[]string{"editor"}, "order:read", nil, false},
+		{[]string{"admin"}, "order:read", nil, true},
+		{[]string{"unknown", "customer"}, "product:read", nil, true},
+	}
+
+	for _, c := range cases {
+		user := &auth.User{ID: "7", Roles: c.roles}
+		if got := a.Allowed(user, c.permission, c.resource); got != c.want {
+			t.Fatalf(`Allowed(%v, %q, %v) = %v, want %v`, c.roles, c.permission, c.resource, got, c.want)
+		}
+	}
+
+	if a.Allowed(nil, "product:read", nil) {
+		t.Fatalf(`Allowed(nil, "product:read") = true, want false`)
+	}
+
+	cyclic := New(Options{
+		Permissions: map[string][]string{"a": {"x"}, "b": {"y"}},
+		Inherits:    map[string][]string{"a": {"b"}, "b": {"a"}},
+	})
+	if !cyclic.Granted([]string{"a"}, "y") || !cyclic.Granted([]string{"b"}, "x") {
+		t.Fatalf(`Granted() with an inheritance cycle = false, want true`)
+	}
+}
+
+func TestCanShouldAnswerRefusedRequests(t *testing.T) {
+	SetInstance(New(Options{
+		Permissions: map[string][]string{"customer": {"product:read"}, "seller": {"product:update"}},
+	}).Define("product:update", func(user *auth.User, resource any) bool {
+		return resource.(*product).Owner == user.ID
+	}))
+	defer SetInstance(nil)
+
+	load := func(r *http.Request) (any, error) {
+		switch router.Param(r, "productId") {
+		case "1":
+			return &product{ID: "1", Owner: "7"}, nil
+		case "2":
+			return &product{ID: "2", Owner: "8"}, nil
+		case "broken":
+			return nil, errors.New("database down")
+		}
+		return nil, nil
+	}
+
+	r := router.Instance()
+	r.Register(
+		router.Get("/authz/product/{productId}", func(w http.ResponseWriter, r *http.Request) {}, Can("product:read")),
+		router.Put("/authz/product/{productId}", func(w http.ResponseWriter, r *http.Request) {}, CanWith("product:update", load)),
+	)
+
+	anonymous := auth.Authenticate()
+	cases := []struct {
+		method string
+		path   string
+		user   *auth.User
+		status int
+	}{
+		{http.MethodGet, "/authz/product/1", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/authz/product/1", &auth.User{ID: "7"}, http.StatusForbidden},
+		{http.MethodGet, "/authz/product/1", &auth.User{ID: "7", Roles: []string{"customer"}}, http.StatusOK},
+		{http.MethodPut, "/authz/product/1", &auth.User{ID: "7", Roles: []string{"seller"}}, http.StatusOK},
+		{http.MethodPut, "/authz/product/2", &auth.User{ID: "7", Roles: []string{"seller"}}, http.StatusForbidden},
+		{http.MethodPut, "/authz/product/3", &auth.User{ID: "7", Roles: []string{"seller"}}, http.StatusNotFound},
+		{http.MethodPut, "/authz/product/broken", &auth.User{ID: "7", Roles: []string{"seller"}}, http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.user != nil {
+			req = auth.WithUser(req, c.user)
+		}
+
+		rec := httptest.NewRecorder()
+		if c.user == nil {
+			anonymous(r.Mux()).ServeHTTP(rec, req)
+		} else {
+			r.Mux().ServeHTTP(rec, req)
+		}
+
+		if rec.Code != c.status {
+			t.Fatalf(`%s %s as %+v status = %d, want %d`, c.method, c.path, c.user, rec.Code, c.status)
+		}
+	}
+}
+
+func TestAuthorizeShouldCheckTheUserOfTheRequest(t *testing.T) {
+	SetInstance(authorizer())
+	defer SetInstance(nil)
+
+	req := auth.WithUser(httptest.NewRequest(http.MethodGet, "/", nil), &auth.User{ID: "7", Roles: []string{"seller"}})
+
+	if !Authorize(req, "product:update", &product{Owner: "7"}) {
+		t.Fatalf(`Authorize() of an own product = false, want true`)
+	}
+
+	if Authorize(req, "product:update", &product{Owner: "8"}) {
+		t.Fatalf(`Authorize() of another product = true, want false`)
+	}
+
+	if Authorize(httptest.NewRequest(http.MethodGet, "/", nil), "product:read", nil) {
+		t.Fatalf(`Authorize() without a user = true, want false`)
+	}
+}
+
+func TestConcurrentFirstCallsShouldShareTheInstance(t *testing.T) {
+	authorizerInstance.Store(nil)
+	defer authorizerInstance.Store(nil)
+
+	instances := make(chan *Authorizer, 8)
+	for i := 0; i < cap(instances); i++ {
+		go func() { instances <- Instance() }()
+	}
+
+	first := <-instances
+	for i := 1; i < cap(instances); i++ {
+		if a := <-instances; a != first {
+			t.Fatalf(`Instance() = %p, want %p`, a, first)
+		}
+	}
+}
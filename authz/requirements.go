@@ -0,0 +1,40 @@
+package authz
+
+import (
+	"github.com/waponix/netgo/router"
+)
+
+// the permissions required by a method of a route
+type Requirement struct {
+	Method      string
+	Path        string
+	Name        string
+	Permissions []string
+}
+
+// Public: lists the permissions required by every method of the routes, e.g. of
+// router.Instance().OrderedRoutes(). The checks passed to the route constructors or added with
+// With() are known without running anything
+func Requirements(rts ...router.RouteInterface) []Requirement {
+	var requirements []Requirement
+
+	for _, rt := range rts {
+		for _, method := range rt.Methods() {
+			permissions := []string{}
+			for _, m := range rt.Declared(method) {
+				if check, ok := m.(*Check); ok {
+					permissions = append(permissions, check.Permission())
+				}
+			}
+
+			requirements = append(requirements, Requirement{
+				Method:      method,
+				Path:        rt.Path(),
+				Name:        rt.Name(),
+				Permissions: permissions,
+			})
+		}
+	}
+
+	return requirements
+}
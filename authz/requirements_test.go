@@ -0,0 +1,41 @@
+package authz
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/router"
+)
+
+func TestRequirementsShouldListThePermissionsOfRoutes(t *testing.T) {
+	called := false
+	handler := func(w http.ResponseWriter, r *http.Request) { called = true }
+	load := func(r *http.Request) (any, error) {
+		t.Fatalf(`the loader was called while listing the requirements`)
+		return nil, nil
+	}
+
+	group := router.Group("/admin",
+		router.Get("/product/{productId}", handler, Can("product:read")).SetName("admin.product.show"),
+		router.Put("/product/{productId}", handler, CanWith("product:update", load)),
+	).Use(auth.RequireAuth()).With(Can("admin"))
+
+	rts := append(group.Routes(), router.Get("/", handler))
+	got := Requirements(rts...)
+
+	want := []Requirement{
+		{Method: router.GET, Path: "/admin/product/{productId}", Name: "admin.product.show", Permissions: []string{"admin", "product:read"}},
+		{Method: router.PUT, Path: "/admin/product/{productId}", Permissions: []string{"admin", "product:update"}},
+		{Method: router.GET, Path: "/", Permissions: []string{}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`Requirements() = %+v, want %+v`, got, want)
+	}
+
+	if called {
+		t.Fatalf(`a handler was called while listing the requirements`)
+	}
+}
@@ -0,0 +1,274 @@
+// Package cookie creates cookies with secure defaults and protects their values, either signed
+// (readable by the client but not changeable) or encrypted (neither readable nor changeable).
+package cookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// browsers do not keep cookies larger than 4KB
+const MAX_SIZE = 4096
+
+// shortest key accepted by Signed(), the size of the HMAC-SHA256 output
+const MIN_SIGNING_KEY_LENGTH = 32
+
+var (
+	ErrInvalid  = errors.New("cookie: the value was changed or protected with an unknown key")
+	ErrExpired  = errors.New("cookie: the value expired")
+	ErrTooLarge = errors.New("cookie: the value does not fit in a cookie")
+	ErrNoKey    = errors.New("cookie: at least one key is required")
+	ErrShortKey = errors.New("cookie: signing keys have to be at least 32 bytes long")
+)
+
+type Options struct {
+	// defaults to "/"
+	Path   string
+	Domain string
+	// how long the cookie is kept, it is also enforced when decoding since clients can ignore it.
+	// Zero makes a session cookie, removed when the browser is closed
+	MaxAge time.Duration
+	// send the cookie over plain http too, for local development only
+	Insecure bool
+	// let JavaScript read the cookie, it is HttpOnly otherwise
+	ScriptAccess bool
+	// defaults to http.SameSiteLaxMode
+	SameSite http.SameSite
+}
+
+// encodes values into cookies and back, the first key protects new values and every key is tried
+// when decoding so that keys can be rotated without logging everybody out
+type Codec struct {
+	options Options
+	// signing keys, or the ciphers of the encryption keys
+	keys  [][]byte
+	aeads []cipher.AEAD
+	now   func() time.Time
+}
+
+// Public: creates a cookie with the secure defaults of the options
+func New(name string, value string, options Options) *http.Cookie {
+	options = withDefaults(options)
+
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		Secure:   !options.Insecure,
+		HttpOnly: !options.ScriptAccess,
+		SameSite: options.SameSite,
+	}
+
+	if options.MaxAge > 0 {
+		cookie.MaxAge = int(options.MaxAge.Seconds())
+	}
+
+	return cookie
+}
+
+// Public: sets a cookie removing the cookie with the name, the options have to match the
+// Path and Domain it was set with
+func Delete(w http.ResponseWriter, name string, options Options) {
+	cookie := New(name, "", options)
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// Public: creates a codec signing values with HMAC-SHA256, keys have to be at least 32 random bytes
+func Signed(options Options, keys ...[]byte) (*Codec, error) {
+	if len(keys) <= 0 {
+		return nil, ErrNoKey
+	}
+
+	for _, key := range keys {
+		if len(key) < MIN_SIGNING_KEY_LENGTH {
+			return nil, ErrShortKey
+		}
+	}
+
+	return &Codec{options: withDefaults(options), keys: keys, now: time.Now}, nil
+}
+
+// Public: creates a codec encrypting values with AES-GCM, keys have to be 16, 24 or 32 random bytes
+func Encrypted(options Options, keys ...[]byte) (*Codec, error) {
+	if len(keys) <= 0 {
+		return nil, ErrNoKey
+	}
+
+	c := &Codec{options: withDefaults(options), now: time.Now}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		c.aeads = append(c.aeads, aead)
+	}
+
+	return c, nil
+}
+
+// encodes the value as JSON and sets it as the cookie with the name
+func (c *Codec) Set(w http.ResponseWriter, name string, value any) error {
+	encoded, err := c.Encode(name, value)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, New(name, encoded, c.options))
+
+	return nil
+}
+
+// decodes the cookie with the name into dst, http.ErrNoCookie is returned when there is no such cookie
+func (c *Codec) Get(r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+
+	return c.Decode(name, cookie.Value, dst)
+}
+
+// removes the cookie with the name
+func (c *Codec) Delete(w http.ResponseWriter, name string) {
+	Delete(w, name, c.options)
+}
+
+// returns the protected JSON encoding of the value, the name is bound to it so that the value
+// of a cookie can not be passed off as the value of another one
+func (c *Codec) Encode(name string, value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Seal(name, data)
+}
+
+// checks the encoded value and decodes its JSON into dst
+func (c *Codec) Decode(name string, encoded string, dst any) error {
+	data, err := c.Open(name, encoded)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+// protects raw data, for values that are not encoded as JSON
+func (c *Codec) Seal(name string, data []byte) (string, error) {
+	// the time of encoding goes along so that MaxAge can be enforced
+	plain := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(data)), uint64(c.now().Unix()))
+	plain = append(plain, data...)
+
+	var encoded string
+	if len(c.aeads) > 0 {
+		aead := c.aeads[0]
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+
+		encoded = base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(name)))
+	} else {
+		payload := base64.RawURLEncoding.EncodeToString(plain)
+		encoded = payload + "." + base64.RawURLEncoding.EncodeToString(sign(c.keys[0], name, payload))
+	}
+
+	if len(name)+len(encoded) > MAX_SIZE {
+		return "", ErrTooLarge
+	}
+
+	return encoded, nil
+}
+
+// returns the data protected by Seal()
+func (c *Codec) Open(name string, encoded string) ([]byte, error) {
+	plain, ok := c.open(name, encoded)
+	if !ok || len(plain) < 8 {
+		return nil, ErrInvalid
+	}
+
+	created := time.Unix(int64(binary.BigEndian.Uint64(plain)), 0)
+	if c.options.MaxAge > 0 && c.now().Sub(created) > c.options.MaxAge {
+		return nil, ErrExpired
+	}
+
+	return plain[8:], nil
+}
+
+func (c *Codec) open(name string, encoded string) ([]byte, bool) {
+	if len(c.aeads) > 0 {
+		sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, false
+		}
+
+		for _, aead := range c.aeads {
+			if len(sealed) < aead.NonceSize() {
+				continue
+			}
+
+			nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+			if plain, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+				return plain, true
+			}
+		}
+
+		return nil, false
+	}
+
+	payload, signature, found := strings.Cut(encoded, ".")
+	if !found {
+		return nil, false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, key := range c.keys {
+		if hmac.Equal(mac, sign(key, name, payload)) {
+			plain, err := base64.RawURLEncoding.DecodeString(payload)
+			return plain, err == nil
+		}
+	}
+
+	return nil, false
+}
+
+func sign(key []byte, name string, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "|" + payload))
+	return mac.Sum(nil)
+}
+
+func withDefaults(options Options) Options {
+	if options.Path == "" {
+		options.Path = "/"
+	}
+
+	if options.SameSite == 0 {
+		options.SameSite = http.SameSiteLaxMode
+	}
+
+	return options
+}
@@ -0,0 +1,150 @@
+package cookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type preferences struct {
+	Theme    string `json:"theme"`
+	PageSize int    `json:"pageSize"`
+}
+
+var (
+	oldKey = []byte("0123456789abcdef0123456789abcdef")
+	newKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func codecs(t *testing.T, options Options, keys ...[]byte) map[string]*Codec {
+	signed, err := Signed(options, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := Encrypted(options, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]*Codec{"signed": signed, "encrypted": encrypted}
+}
+
+func TestCodecShouldRoundTripStructs(t *testing.T) {
+	for kind, c := range codecs(t, Options{MaxAge: time.Hour}, oldKey) {
+		rec := httptest.NewRecorder()
+		if err := c.Set(rec, "prefs", preferences{Theme: "dark", PageSize: 50}); err != nil {
+			t.Fatalf(`%s: Set() error = %v, want nil`, kind, err)
+		}
+
+		set := rec.Result().Cookies()[0]
+		if !set.Secure || !set.HttpOnly || set.SameSite != http.SameSiteLaxMode || set.Path != "/" || set.MaxAge != 3600 {
+			t.Fatalf(`%s: cookie = %+v, want the secure defaults`, kind, set)
+		}
+
+		if kind == "encrypted" && strings.Contains(set.Value, "dark") {
+			t.Fatalf(`encrypted cookie value = %q, want the value hidden`, set.Value)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(set)
+
+		var got preferences
+		if err := c.Get(req, "prefs", &got); err != nil || got != (preferences{Theme: "dark", PageSize: 50}) {
+			t.Fatalf(`%s: Get() = %+v, %v, want the encoded preferences`, kind, got, err)
+		}
+
+		if err := c.Get(httptest.NewRequest(http.MethodGet, "/", nil), "prefs", &got); !errors.Is(err, http.ErrNoCookie) {
+			t.Fatalf(`%s: Get() without the cookie error = %v, want http.ErrNoCookie`, kind, err)
+		}
+	}
+}
+
+func TestCodecShouldRejectTamperedValues(t *testing.T) {
+	for kind, c := range codecs(t, Options{}, oldKey) {
+		encoded, _ := c.Encode("role", "user")
+
+		forged := []struct {
+			name    string
+			encoded string
+		}{
+			{"role", encoded[:len(encoded)-3] + "abc"},
+			{"role", "garbage"},
+			{"role", ""},
+			// the value of a cookie can not be replayed as another cookie
+			{"admin", encoded},
+		}
+
+		for _, f := range forged {
+			var role string
+			if err := c.Decode(f.name, f.encoded, &role); !errors.Is(err, ErrInvalid) {
+				t.Fatalf(`%s: Decode(%q, %q) error = %v, want ErrInvalid`, kind, f.name, f.encoded, err)
+			}
+		}
+	}
+}
+
+func TestCodecShouldSupportKeyRotation(t *testing.T) {
+	before := codecs(t, Options{}, oldKey)
+	after := codecs(t, Options{}, newKey, oldKey)
+	dropped := codecs(t, Options{}, newKey)
+
+	for kind := range before {
+		encoded, _ := before[kind].Encode("role", "user")
+
+		var role string
+		if err := after[kind].Decode("role", encoded, &role); err != nil || role != "user" {
+			t.Fatalf(`%s: Decode() with the old key second = %q, %v, want "user"`, kind, role, err)
+		}
+
+		if err := dropped[kind].Decode("role", encoded, &role); !errors.Is(err, ErrInvalid) {
+			t.Fatalf(`%s: Decode() after dropping the old key error = %v, want ErrInvalid`, kind, err)
+		}
+	}
+}
+
+func TestCodecShouldEnforceMaxAge(t *testing.T) {
+	for kind, c := range codecs(t, Options{MaxAge: time.Minute}, oldKey) {
+		encoded, _ := c.Encode("role", "user")
+
+		c.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		var role string
+		if err := c.Decode("role", encoded, &role); !errors.Is(err, ErrExpired) {
+			t.Fatalf(`%s: Decode() of an old value error = %v, want ErrExpired`, kind, err)
+		}
+
+		if _, err := c.Encode("big", strings.Repeat("x", MAX_SIZE)); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf(`%s: Encode() of a large value error = %v, want ErrTooLarge`, kind, err)
+		}
+	}
+}
+
+func TestKeysShouldBeValidated(t *testing.T) {
+	if _, err := Signed(Options{}); !errors.Is(err, ErrNoKey) {
+		t.Fatalf(`Signed() without keys error = %v, want ErrNoKey`, err)
+	}
+
+	for _, key := range [][]byte{nil, []byte("short"), oldKey[:MIN_SIGNING_KEY_LENGTH-1]} {
+		if _, err := Signed(Options{}, oldKey, key); !errors.Is(err, ErrShortKey) {
+			t.Fatalf(`Signed() with a %d byte key error = %v, want ErrShortKey`, len(key), err)
+		}
+	}
+
+	if _, err := Encrypted(Options{}, []byte("short")); err == nil {
+		t.Fatalf(`Encrypted() with a 5 byte key error = nil, want an error`)
+	}
+}
+
+func TestDeleteShouldExpireTheCookie(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Delete(rec, "prefs", Options{Path: "/app", Insecure: true, ScriptAccess: true})
+
+	set := rec.Result().Cookies()[0]
+	if set.MaxAge >= 0 || set.Path != "/app" || set.Secure || set.HttpOnly {
+		t.Fatalf(`deleted cookie = %+v, want an expired cookie with the given attributes`, set)
+	}
+}
@@ -0,0 +1,211 @@
+// Package cors answers cross-origin requests according to a policy, applied to every route with
+// router.Use() or to a group of routes with router.Group().Use()
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+// allows any origin, or any request header in AllowedHeaders
+const ALL = "*"
+
+type Options struct {
+	// origins like https://app.example.com, wildcards like https://*.example.com, or ALL. Origins only
+	// allowed through ALL never get credentials, list the origins that need them
+	AllowedOrigins []string
+	// regular expressions matched against the whole origin
+	AllowedOriginPatterns []string
+	// decides on origins not allowed by the lists above
+	AllowOriginFunc func(origin string, r *http.Request) bool
+	// methods allowed in preflight requests, defaults to DefaultMethods()
+	AllowedMethods []string
+	// headers the client may send, ALL allows any header. Defaults to DefaultHeaders()
+	AllowedHeaders []string
+	// response headers the client is allowed to read besides the CORS-safelisted ones
+	ExposedHeaders []string
+	// allow cookies and the Authorization header to be sent along
+	AllowCredentials bool
+	// how long the answer to a preflight request may be cached, not sent when zero
+	MaxAge time.Duration
+}
+
+// Public: returns the methods allowed when AllowedMethods is empty, a new slice on every call
+func DefaultMethods() []string {
+	return []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+}
+
+// Public: returns the headers allowed when AllowedHeaders is empty, a new slice on every call so
+// that it can be appended to: append(cors.DefaultHeaders(), "X-CSRF-Token")
+func DefaultHeaders() []string {
+	return []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "Authorization", "X-Requested-With"}
+}
+
+type policy struct {
+	options  Options
+	any      bool
+	exact    []string
+	patterns []*regexp.Regexp
+	headers  []string
+}
+
+// Public: creates the middleware applying the policy, preflight requests are answered without
+// reaching the handler and refused with 403 when the origin, method or headers are not allowed
+func New(options Options) func(http.Handler) http.Handler {
+	if len(options.AllowedMethods) <= 0 {
+		options.AllowedMethods = DefaultMethods()
+	}
+
+	if len(options.AllowedHeaders) <= 0 {
+		options.AllowedHeaders = DefaultHeaders()
+	}
+
+	p := &policy{options: options}
+
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.ToLower(origin)
+
+		switch {
+		case origin == ALL:
+			p.any = true
+		case strings.Contains(origin, "*"):
+			// a wildcard stands for one or more labels of the host name
+			pattern := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`)
+			p.patterns = append(p.patterns, regexp.MustCompile("^"+pattern+"$"))
+		default:
+			p.exact = append(p.exact, origin)
+		}
+	}
+
+	for _, pattern := range options.AllowedOriginPatterns {
+		p.patterns = append(p.patterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+
+	for _, header := range options.AllowedHeaders {
+		p.headers = append(p.headers, strings.ToLower(header))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the answer depends on the origin, shared caches have to keep one per origin
+			w.Header().Add("Vary", "Origin")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				p.preflight(w, r, origin)
+				return
+			}
+
+			if p.allowsOrigin(origin, r) {
+				p.setOrigin(w, r, origin)
+				if len(options.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(options.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p *policy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	requested := requestedHeaders(r)
+
+	if !p.allowsOrigin(origin, r) || !sliceUtil.Contains(p.options.AllowedMethods, method) || !p.allowsHeaders(requested) {
+		router.Error(w, r, http.StatusForbidden)
+		return
+	}
+
+	p.setOrigin(w, r, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(p.options.AllowedMethods, ", "))
+	if len(requested) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+
+	if p.options.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.options.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *policy) allowsOrigin(origin string, r *http.Request) bool {
+	return p.any || p.listsOrigin(origin, r)
+}
+
+// reports whether the origin is allowed by something else than ALL
+func (p *policy) listsOrigin(origin string, r *http.Request) bool {
+	lowered := strings.ToLower(origin)
+
+	if sliceUtil.Contains(p.exact, lowered) {
+		return true
+	}
+
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(lowered) {
+			return true
+		}
+	}
+
+	return p.options.AllowOriginFunc != nil && p.options.AllowOriginFunc(origin, r)
+}
+
+func (p *policy) allowsHeaders(requested []string) bool {
+	if sliceUtil.Contains(p.headers, ALL) {
+		return true
+	}
+
+	for _, header := range requested {
+		if !sliceUtil.Contains(p.headers, header) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *policy) setOrigin(w http.ResponseWriter, r *http.Request, origin string) {
+	// echoing any origin back along with credentials would let every site read the responses of
+	// logged in users, origins only allowed through ALL get a wildcard without credentials
+	if !p.options.AllowCredentials || !p.listsOrigin(origin, r) {
+		if p.any {
+			w.Header().Set("Access-Control-Allow-Origin", ALL)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+}
+
+// the lowercased names listed in the Access-Control-Request-Headers header
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.ToLower(strings.TrimSpace(header)); header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,126 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(options Options, method string, headers map[string]string) *httptest.ResponseRecorder {
+	handler := New(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("handler"))
+	}))
+
+	req := httptest.NewRequest(method, "/api/product/1", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOriginsShouldBeMatched(t *testing.T) {
+	options := Options{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`https://review-[0-9]+\.example\.net`},
+		AllowOriginFunc: func(origin string, r *http.Request) bool {
+			return strings.HasSuffix(origin, ".localhost:3000")
+		},
+	}
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://admin.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evil.com/.example.org", false},
+		{"https://review-42.example.net", true},
+		{"https://review-x.example.net", false},
+		{"http://app.localhost:3000", true},
+	}
+
+	for _, c := range cases {
+		rec := serve(options, http.MethodGet, map[string]string{"Origin": c.origin})
+
+		if allowed := rec.Header().Get("Access-Control-Allow-Origin") == c.origin; allowed != c.allowed {
+			t.Fatalf(`origin %q allowed = %v, want %v`, c.origin, allowed, c.allowed)
+		}
+
+		if rec.Body.String() != "handler" || rec.Header().Get("Vary") != "Origin" {
+			t.Fatalf(`GET from %q = %q with Vary %q, want the handler to run with Vary "Origin"`, c.origin, rec.Body.String(), rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestRequestsShouldGetCORSHeaders(t *testing.T) {
+	rec := serve(Options{AllowedOrigins: []string{ALL}, ExposedHeaders: []string{"X-Total-Count"}}, http.MethodGet, map[string]string{"Origin": "https://app.example.com"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != ALL || rec.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" {
+		t.Fatalf(`headers = %v, want a wildcard origin and the exposed headers`, rec.Header())
+	}
+
+	// any site could read the responses of logged in users if ALL allowed credentials
+	rec = serve(Options{AllowedOrigins: []string{ALL}, AllowCredentials: true}, http.MethodGet, map[string]string{"Origin": "https://app.example.com"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != ALL || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf(`headers with credentials for ALL = %v, want a wildcard origin without credentials`, rec.Header())
+	}
+
+	rec = serve(Options{AllowedOrigins: []string{ALL, "https://app.example.com"}, AllowCredentials: true}, http.MethodGet, map[string]string{"Origin": "https://app.example.com"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf(`headers with credentials for a listed origin = %v, want the origin echoed back`, rec.Header())
+	}
+
+	rec = serve(Options{AllowedOrigins: []string{ALL}}, http.MethodGet, nil)
+	if rec.Header().Get("Vary") != "" || rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Body.String() != "handler" {
+		t.Fatalf(`same origin request headers = %v, want none`, rec.Header())
+	}
+}
+
+func TestPreflightShouldBeAnswered(t *testing.T) {
+	options := Options{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		MaxAge:         10 * time.Minute,
+	}
+
+	rec := serve(options, http.MethodOptions, map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  http.MethodPut,
+		"Access-Control-Request-Headers": "Content-Type, authorization",
+	})
+
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, PUT",
+		"Access-Control-Allow-Headers": "content-type, authorization",
+		"Access-Control-Max-Age":       "600",
+	}
+	for key, value := range want {
+		if rec.Header().Get(key) != value {
+			t.Fatalf(`preflight %s = %q, want %q`, key, rec.Header().Get(key), value)
+		}
+	}
+
+	if rec.Code != http.StatusNoContent || rec.Body.String() != "" {
+		t.Fatalf(`preflight = %d %q, want 204 without reaching the handler`, rec.Code, rec.Body.String())
+	}
+
+	refused := []map[string]string{
+		{"Origin": "https://evil.com", "Access-Control-Request-Method": http.MethodPut},
+		{"Origin": "https://app.example.com", "Access-Control-Request-Method": http.MethodDelete},
+		{"Origin": "https://app.example.com", "Access-Control-Request-Method": http.MethodPut, "Access-Control-Request-Headers": "X-Custom"},
+	}
+	for _, headers := range refused {
+		if rec := serve(options, http.MethodOptions, headers); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Fatalf(`preflight %v = %d, want 403 without CORS headers`, headers, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package csrf refuses state changing requests that do not carry the token handed to the client,
+// so that other sites can not make the browser of a user post forms on their behalf.
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/waponix/netgo/cookie"
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/session"
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+// mode constants, decides where the token of the client is kept
+const (
+	MODE_DOUBLE_SUBMIT = "DOUBLE_SUBMIT" // in a cookie, the request has to repeat it in a header or a field
+	MODE_SYNCHRONIZER  = "SYNCHRONIZER"  // in the session, requires the session middleware to run first
+)
+
+const (
+	DEFAULT_COOKIE_NAME = "csrf"
+	DEFAULT_HEADER      = "X-CSRF-Token"
+	DEFAULT_FIELD       = "csrf_token"
+)
+
+const (
+	tokenLength = 32
+	// session key of the token in MODE_SYNCHRONIZER
+	sessionKey = "_csrf"
+)
+
+type Options struct {
+	// defaults to MODE_DOUBLE_SUBMIT
+	Mode string
+	// cookie holding the token in MODE_DOUBLE_SUBMIT
+	CookieName    string
+	CookieOptions cookie.Options
+	// header the token is read from, the token is also sent in it with every safe request
+	// so that scripts can pick it up
+	Header string
+	// form field the token is read from
+	Field string
+	// names or paths of routes that are not checked, e.g. "webhook.payment" or "/webhooks/{provider}"
+	ExemptRoutes []string
+	// optional, return true to skip the check of the request
+	Exempt func(*http.Request) bool
+	// origins besides the one of the request allowed to post, e.g. https://admin.example.com,
+	// a wildcard stands for one or more labels of the host name: https://*.example.com
+	TrustedOrigins []string
+}
+
+type contextKey int
+
+const (
+	tokenKey contextKey = iota
+)
+
+// what the handlers need to hand the token out, the token of a client that has none yet is only
+// created when a handler asks for it so that visitors never shown a form get no cookie nor session
+type requestToken struct {
+	token   []byte
+	w       http.ResponseWriter
+	session *session.Session
+	options Options
+}
+
+// Public: creates the middleware checking the token of every request made with a method other
+// than GET, HEAD, OPTIONS and TRACE. Refused requests are answered with 403 through router.Error()
+func New(options Options) func(http.Handler) http.Handler {
+	if options.Mode == "" {
+		options.Mode = MODE_DOUBLE_SUBMIT
+	}
+
+	if options.CookieName == "" {
+		options.CookieName = DEFAULT_COOKIE_NAME
+	}
+
+	if options.Header == "" {
+		options.Header = DEFAULT_HEADER
+	}
+
+	if options.Field == "" {
+		options.Field = DEFAULT_FIELD
+	}
+
+	trusted := compileOrigins(options.TrustedOrigins)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if options.Mode == MODE_SYNCHRONIZER && session.FromRequest(r) == nil {
+				logger.FromRequest(r).Error("csrf: " + options.Mode + " mode requires the session middleware to run first")
+				router.Error(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			token := storedToken(r, options)
+			rt := &requestToken{token: token, w: w, session: session.FromRequest(r), options: options}
+			r = r.WithContext(context.WithValue(r.Context(), tokenKey, rt))
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				if token != nil {
+					w.Header().Set(options.Header, mask(token))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if exempt(r, options) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if reason := check(r, token, options, trusted); reason != "" {
+				logger.FromRequest(r).Notice("csrf: refused " + r.Method + " " + r.URL.Path + ", " + reason)
+				router.Error(w, r, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Public: returns a token to send along with the next form, every call returns a different
+// token so that it can not be guessed from compressed responses. Call it before the response is
+// written, the first call of a client gets its token stored. Empty outside of the middleware
+func Token(r *http.Request) string {
+	rt, ok := r.Context().Value(tokenKey).(*requestToken)
+	if !ok {
+		return ""
+	}
+
+	return mask(rt.get())
+}
+
+// Public: returns the hidden form field carrying a token, for templates: {{ .CSRFField }}
+func TemplateField(r *http.Request) template.HTML {
+	rt, ok := r.Context().Value(tokenKey).(*requestToken)
+	if !ok {
+		return ""
+	}
+
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(rt.options.Field) + `" value="` + mask(rt.get()) + `">`)
+}
+
+// returns the token of the client, storing a new one in the session or the cookie when there is none yet
+func (rt *requestToken) get() []byte {
+	if rt.token != nil {
+		return rt.token
+	}
+
+	rt.token = randomBytes(tokenLength)
+	encoded := base64.RawURLEncoding.EncodeToString(rt.token)
+
+	if rt.options.Mode == MODE_SYNCHRONIZER {
+		rt.session.Set(sessionKey, encoded)
+	} else {
+		http.SetCookie(rt.w, cookie.New(rt.options.CookieName, encoded, rt.options.CookieOptions))
+	}
+
+	rt.w.Header().Set(rt.options.Header, mask(rt.token))
+
+	return rt.token
+}
+
+// returns the token the client already has, nil when it has none
+func storedToken(r *http.Request, options Options) []byte {
+	var encoded string
+	if options.Mode == MODE_SYNCHRONIZER {
+		encoded = session.FromRequest(r).GetString(sessionKey)
+	} else if c, err := r.Cookie(options.CookieName); err == nil {
+		encoded = c.Value
+	}
+
+	if token, err := base64.RawURLEncoding.DecodeString(encoded); err == nil && len(token) == tokenLength {
+		return token
+	}
+
+	return nil
+}
+
+// returns why the request is refused, empty when it is not
+func check(r *http.Request, token []byte, options Options, trusted []*regexp.Regexp) string {
+	// browsers send the Origin of cross-site posts, older ones only the Referer
+	source := r.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = r.Header.Get("Referer")
+	}
+
+	if source != "" && !allowedOrigin(r, source, trusted) {
+		return "origin " + source + " is not trusted"
+	}
+
+	submitted := r.Header.Get(options.Header)
+	if submitted == "" {
+		submitted = r.PostFormValue(options.Field)
+	}
+
+	if submitted == "" || token == nil {
+		return "no token"
+	}
+
+	if !matches(submitted, token) {
+		return "invalid token"
+	}
+
+	return ""
+}
+
+// the scheme is not compared for the host of the request since it is often lost behind a proxy terminating TLS
+func allowedOrigin(r *http.Request, source string, trusted []*regexp.Regexp) bool {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, pattern := range trusted {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compileOrigins(origins []string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, origin := range origins {
+		// a wildcard stands for one or more labels of the host name
+		pattern := regexp.QuoteMeta(strings.ToLower(strings.TrimSuffix(origin, "/")))
+		pattern = strings.ReplaceAll(pattern, `\*`, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`)
+		patterns = append(patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	return patterns
+}
+
+func exempt(r *http.Request, options Options) bool {
+	if options.Exempt != nil && options.Exempt(r) {
+		return true
+	}
+
+	rt := router.CurrentRoute(r)
+	if rt == nil {
+		return false
+	}
+
+	return sliceUtil.Contains(options.ExemptRoutes, rt.Path()) || (rt.Name() != "" && sliceUtil.Contains(options.ExemptRoutes, rt.Name()))
+}
+
+// xors the token with a random pad sent along, the result changes on every call
+func mask(token []byte) string {
+	pad := randomBytes(len(token))
+	masked := make([]byte, 0, 2*len(token))
+	masked = append(masked, pad...)
+
+	for i := range token {
+		masked = append(masked, pad[i]^token[i])
+	}
+
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func matches(submitted string, token []byte) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil || len(masked) != 2*len(token) {
+		return false
+	}
+
+	pad, xored := masked[:len(token)], masked[len(token):]
+	unmasked := make([]byte, len(token))
+	for i := range token {
+		unmasked[i] = pad[i] ^ xored[i]
+	}
+
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
@@ -0,0 +1,189 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/session"
+)
+
+// a client keeping its cookies between requests
+type client struct {
+	handler http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func newClient(handler http.Handler) *client {
+	return &client{handler: handler, cookies: map[string]*http.Cookie{}}
+}
+
+func (c *client) do(req *http.Request) *httptest.ResponseRecorder {
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+
+	for _, cookie := range rec.Result().Cookies() {
+		c.cookies[cookie.Name] = cookie
+	}
+
+	return rec
+}
+
+func (c *client) post(form url.Values, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/product", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	return c.do(req)
+}
+
+var saved = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("saved " + TemplateField(r)[:6]))
+})
+
+func TestModesShouldCheckTheToken(t *testing.T) {
+	sessions := session.NewManager(session.Options{GCInterval: -1})
+	defer sessions.Close()
+
+	handlers := map[string]http.Handler{
+		MODE_DOUBLE_SUBMIT: New(Options{})(saved),
+		MODE_SYNCHRONIZER:  sessions.Middleware(New(Options{Mode: MODE_SYNCHRONIZER})(saved)),
+	}
+
+	for mode, handler := range handlers {
+		c := newClient(handler)
+
+		if rec := c.post(url.Values{"name": {"chair"}}, nil); rec.Code != http.StatusForbidden {
+			t.Fatalf(`%s: POST without a token = %d, want 403`, mode, rec.Code)
+		}
+
+		token := c.do(httptest.NewRequest(http.MethodGet, "http://example.com/product", nil)).Header().Get(DEFAULT_HEADER)
+		if token == "" {
+			t.Fatalf(`%s: GET did not expose the token in %s`, mode, DEFAULT_HEADER)
+		}
+
+		if rec := c.post(url.Values{DEFAULT_FIELD: {token}}, nil); rec.Code != http.StatusOK || rec.Body.String() != "saved <input" {
+			t.Fatalf(`%s: POST with the token field = %d %q, want 200`, mode, rec.Code, rec.Body.String())
+		}
+
+		if rec := c.post(nil, map[string]string{DEFAULT_HEADER: token}); rec.Code != http.StatusOK {
+			t.Fatalf(`%s: POST with the token header = %d, want 200`, mode, rec.Code)
+		}
+
+		// a token of another client is of no use
+		other := newClient(handler)
+		otherToken := other.do(httptest.NewRequest(http.MethodGet, "http://example.com/product", nil)).Header().Get(DEFAULT_HEADER)
+		if rec := c.post(url.Values{DEFAULT_FIELD: {otherToken}}, nil); rec.Code != http.StatusForbidden {
+			t.Fatalf(`%s: POST with the token of another client = %d, want 403`, mode, rec.Code)
+		}
+	}
+}
+
+func TestSynchronizerModeShouldRequireSessions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(Options{Mode: MODE_SYNCHRONIZER})(saved).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf(`GET without the session middleware = %d, want 500`, rec.Code)
+	}
+}
+
+func TestTokensShouldBeMasked(t *testing.T) {
+	c := newClient(New(Options{})(saved))
+
+	first := c.do(httptest.NewRequest(http.MethodGet, "http://example.com/", nil)).Header().Get(DEFAULT_HEADER)
+	second := c.do(httptest.NewRequest(http.MethodGet, "http://example.com/", nil)).Header().Get(DEFAULT_HEADER)
+
+	if first == second {
+		t.Fatalf(`tokens of two responses = %q, want them to differ`, first)
+	}
+
+	for _, token := range []string{first, second} {
+		if rec := c.post(nil, map[string]string{DEFAULT_HEADER: token}); rec.Code != http.StatusOK {
+			t.Fatalf(`POST with token %q = %d, want 200`, token, rec.Code)
+		}
+	}
+}
+
+func TestOriginShouldBeChecked(t *testing.T) {
+	c := newClient(New(Options{TrustedOrigins: []string{"https://admin.example.com"}})(saved))
+	token := c.do(httptest.NewRequest(http.MethodGet, "http://example.com/", nil)).Header().Get(DEFAULT_HEADER)
+
+	cases := []struct {
+		headers map[string]string
+		status  int
+	}{
+		{map[string]string{"Origin": "https://example.com"}, http.StatusOK},
+		{map[string]string{"Origin": "https://admin.example.com"}, http.StatusOK},
+		{map[string]string{"Origin": "https://evil.com"}, http.StatusForbidden},
+		{map[string]string{"Referer": "https://evil.com/form"}, http.StatusForbidden},
+		{map[string]string{"Origin": "null", "Referer": "https://example.com/form"}, http.StatusOK},
+	}
+
+	for _, test := range cases {
+		test.headers[DEFAULT_HEADER] = token
+		if rec := c.post(nil, test.headers); rec.Code != test.status {
+			t.Fatalf(`POST with %v = %d, want %d`, test.headers, rec.Code, test.status)
+		}
+	}
+}
+
+func TestExemptRoutesShouldNotBeChecked(t *testing.T) {
+	r := router.Instance().
+		Use(New(Options{ExemptRoutes: []string{"webhook", "/callbacks/{provider}"}})).
+		Register(
+			router.Post("/webhooks/payment", saved).SetName("webhook"),
+			router.Post("/callbacks/{provider}", saved),
+			router.Post("/product", saved),
+		)
+
+	for path, status := range map[string]int{"/webhooks/payment": http.StatusOK, "/callbacks/github": http.StatusOK, "/product": http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		r.Mux().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+
+		if rec.Code != status {
+			t.Fatalf(`POST %s = %d, want %d`, path, rec.Code, status)
+		}
+	}
+}
+
+func TestTokenShouldOnlyBeStoredWhenAskedFor(t *testing.T) {
+	sessions := session.NewManager(session.Options{GCInterval: -1})
+	defer sessions.Close()
+
+	browse := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("product"))
+	})
+
+	handlers := map[string]http.Handler{
+		MODE_DOUBLE_SUBMIT: New(Options{})(browse),
+		MODE_SYNCHRONIZER:  sessions.Middleware(New(Options{Mode: MODE_SYNCHRONIZER})(browse)),
+	}
+
+	for mode, handler := range handlers {
+		rec := newClient(handler).do(httptest.NewRequest(http.MethodGet, "http://example.com/product", nil))
+		if len(rec.Result().Cookies()) > 0 || rec.Header().Get(DEFAULT_HEADER) != "" {
+			t.Fatalf(`%s: GET without asking for a token = %v, want no cookie and no token`, mode, rec.Header())
+		}
+	}
+}
+
+func TestTrustedOriginsShouldAcceptWildcards(t *testing.T) {
+	c := newClient(New(Options{TrustedOrigins: []string{"https://*.example.org"}})(saved))
+	token := c.do(httptest.NewRequest(http.MethodGet, "http://example.com/", nil)).Header().Get(DEFAULT_HEADER)
+
+	for origin, status := range map[string]int{"https://admin.example.org": http.StatusOK, "https://example.org": http.StatusForbidden, "https://admin.example.org.evil.com": http.StatusForbidden} {
+		if rec := c.post(nil, map[string]string{"Origin": origin, DEFAULT_HEADER: token}); rec.Code != status {
+			t.Fatalf(`POST from %s = %d, want %d`, origin, rec.Code, status)
+		}
+	}
+}
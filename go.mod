@@ -1,5 +1,5 @@
 module github.com/waponix/netgo
 
-go 1.21.3
+go 1.23
 
-require github.com/gorilla/mux v1.8.0 // indirect
+require github.com/gorilla/mux v1.8.0
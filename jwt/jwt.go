@@ -0,0 +1,261 @@
+// Package jwt signs and verifies JSON Web Tokens (RFC 7519) with HS256, RS256, ES256 and EdDSA keys,
+// which can be loaded from a local JWKS file.
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+const (
+	DEFAULT_TTL    = 15 * time.Minute
+	DEFAULT_LEEWAY = 30 * time.Second
+)
+
+var (
+	ErrMalformed    = errors.New("jwt: malformed token")
+	ErrAlgorithm    = errors.New("jwt: algorithm not accepted")
+	ErrUnknownKey   = errors.New("jwt: unknown key")
+	ErrSignature    = errors.New("jwt: invalid signature")
+	ErrExpired      = errors.New("jwt: token expired")
+	ErrNotYetValid  = errors.New("jwt: token not valid yet")
+	ErrIssuer       = errors.New("jwt: unexpected issuer")
+	ErrAudience     = errors.New("jwt: unexpected audience")
+	ErrNoExpiration = errors.New("jwt: token without expiration")
+)
+
+type Options struct {
+	// the iss claim of issued tokens, verified tokens have to carry it when set
+	Issuer string
+	// the aud claim of issued tokens, verified tokens have to be meant for one of them when set
+	Audience []string
+	// how long issued tokens are valid, defaults to DEFAULT_TTL
+	TTL time.Duration
+	// tolerated difference between the clocks of the issuer and the verifier, defaults to
+	// DEFAULT_LEEWAY, negative for none
+	Leeway time.Duration
+	// algorithms accepted when verifying, any algorithm of the keys when empty
+	Algorithms []string
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type Signer struct {
+	key     Key
+	options Options
+	now     func() time.Time
+}
+
+type Verifier struct {
+	keys    *KeySet
+	options Options
+	now     func() time.Time
+}
+
+// Public: creates a signer issuing tokens with the key
+func NewSigner(key Key, options Options) *Signer {
+	return &Signer{key: key, options: withDefaults(options), now: time.Now}
+}
+
+// Public: creates a verifier accepting tokens signed by one of the keys
+func NewVerifier(keys *KeySet, options Options) *Verifier {
+	return &Verifier{keys: keys, options: withDefaults(options), now: time.Now}
+}
+
+// signs the claims, iat, exp, jti, iss and aud are added unless the claims have them already
+func (s *Signer) Sign(claims map[string]any) (string, error) {
+	now := s.now()
+
+	payload := make(map[string]any, len(claims)+5)
+	payload["iat"] = now.Unix()
+	payload["exp"] = now.Add(s.options.TTL).Unix()
+	payload["jti"] = randomID()
+
+	if s.options.Issuer != "" {
+		payload["iss"] = s.options.Issuer
+	}
+
+	switch len(s.options.Audience) {
+	case 0:
+	case 1:
+		payload["aud"] = s.options.Audience[0]
+	default:
+		payload["aud"] = s.options.Audience
+	}
+
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	headerJSON, err := json.Marshal(header{Alg: s.key.Algorithm, Typ: "JWT", Kid: s.key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	input := encodeSegment(headerJSON) + "." + encodeSegment(payloadJSON)
+	signature, err := s.key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + encodeSegment(signature), nil
+}
+
+// checks the signature and the claims of the token and returns the claims, numbers are float64
+func (v *Verifier) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, err := v.key(h)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrSignature
+	}
+
+	claims := map[string]any{}
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// the key the token claims to be signed with, its algorithm has to be the one of the header so
+// that a public key can never be used as an HMAC secret
+func (v *Verifier) key(h header) (Key, error) {
+	if len(v.options.Algorithms) > 0 && !sliceUtil.Contains(v.options.Algorithms, h.Alg) {
+		return Key{}, fmt.Errorf("%w: %q", ErrAlgorithm, h.Alg)
+	}
+
+	if h.Kid != "" {
+		key, ok := v.keys.Find(h.Kid)
+		if !ok {
+			return Key{}, fmt.Errorf("%w: %q", ErrUnknownKey, h.Kid)
+		}
+
+		if key.Algorithm != h.Alg {
+			return Key{}, fmt.Errorf("%w: %q for key %q", ErrAlgorithm, h.Alg, h.Kid)
+		}
+
+		return key, nil
+	}
+
+	// without a kid the only key of the algorithm is used
+	var found []Key
+	for _, key := range v.keys.Keys() {
+		if key.Algorithm == h.Alg {
+			found = append(found, key)
+		}
+	}
+
+	if len(found) != 1 {
+		return Key{}, fmt.Errorf("%w: no kid and %d keys for %q", ErrUnknownKey, len(found), h.Alg)
+	}
+
+	return found[0], nil
+}
+
+func (v *Verifier) validate(claims map[string]any) error {
+	now := v.now()
+	leeway := v.options.Leeway
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return ErrNoExpiration
+	}
+
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return ErrExpired
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-leeway)) {
+		return ErrNotYetValid
+	}
+
+	if v.options.Issuer != "" && claims["iss"] != v.options.Issuer {
+		return ErrIssuer
+	}
+
+	if len(v.options.Audience) > 0 {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []any:
+			for _, a := range aud {
+				if s, ok := a.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+
+		if len(sliceUtil.Intersect(audiences, v.options.Audience)) <= 0 {
+			return ErrAudience
+		}
+	}
+
+	return nil
+}
+
+func decodeJSON(segment string, v any) error {
+	data, err := decodeSegment(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func withDefaults(options Options) Options {
+	if options.TTL <= 0 {
+		options.TTL = DEFAULT_TTL
+	}
+
+	if options.Leeway == 0 {
+		options.Leeway = DEFAULT_LEEWAY
+	} else if options.Leeway < 0 {
+		options.Leeway = 0
+	}
+
+	return options
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return encodeSegment(b)
+}
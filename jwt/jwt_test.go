@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKeys(t *testing.T) []Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []Key{
+		{ID: "hs", Algorithm: HS256, Material: []byte("0123456789abcdef0123456789abcdef")},
+		{ID: "rs", Algorithm: RS256, Material: rsaKey},
+		{ID: "es", Algorithm: ES256, Material: ecKey},
+		{ID: "ed", Algorithm: EDDSA, Material: edKey},
+	}
+}
+
+func TestTokensShouldBeSignedAndVerified(t *testing.T) {
+	keys := testKeys(t)
+	verifier := NewVerifier(NewKeySet(keys...), Options{})
+
+	for _, key := range keys {
+		token, err := NewSigner(key, Options{}).Sign(map[string]any{"sub": "42", "roles": []string{"admin"}})
+		if err != nil {
+			t.Fatalf(`%s: Sign() error = %v, want nil`, key.Algorithm, err)
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil || claims["sub"] != "42" || claims["jti"] == "" {
+			t.Fatalf(`%s: Verify() = %v, %v, want the claims`, key.Algorithm, claims, err)
+		}
+
+		// flip a character of the signature
+		parts := strings.Split(token, ".")
+		signature := []byte(parts[2])
+		signature[5] ^= 1
+		if _, err := verifier.Verify(parts[0] + "." + parts[1] + "." + string(signature)); err == nil {
+			t.Fatalf(`%s: Verify() of a tampered token error = nil, want an error`, key.Algorithm)
+		}
+	}
+}
+
+func TestVerifyShouldRefuseOtherAlgorithms(t *testing.T) {
+	keys := testKeys(t)
+
+	// a token signed with HS256 using the kid of the RSA key must not be accepted
+	forged, _ := NewSigner(Key{ID: "rs", Algorithm: HS256, Material: []byte("guess")}, Options{}).Sign(nil)
+	if _, err := NewVerifier(NewKeySet(keys...), Options{}).Verify(forged); !errors.Is(err, ErrAlgorithm) {
+		t.Fatalf(`Verify() with a switched algorithm error = %v, want ErrAlgorithm`, err)
+	}
+
+	token, _ := NewSigner(keys[0], Options{}).Sign(nil)
+	if _, err := NewVerifier(NewKeySet(keys...), Options{Algorithms: []string{RS256}}).Verify(token); !errors.Is(err, ErrAlgorithm) {
+		t.Fatalf(`Verify() of HS256 accepting RS256 only error = %v, want ErrAlgorithm`, err)
+	}
+
+	none := encodeSegment([]byte(`{"alg":"none"}`)) + "." + encodeSegment([]byte(`{"sub":"42"}`)) + "."
+	if _, err := NewVerifier(NewKeySet(keys...), Options{}).Verify(none); err == nil {
+		t.Fatalf(`Verify() of an unsigned token error = nil, want an error`)
+	}
+
+	unknown, _ := NewSigner(Key{ID: "other", Algorithm: HS256, Material: []byte("secret")}, Options{}).Sign(nil)
+	if _, err := NewVerifier(NewKeySet(keys...), Options{}).Verify(unknown); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf(`Verify() with an unknown kid error = %v, want ErrUnknownKey`, err)
+	}
+}
+
+func TestVerifyShouldValidateClaims(t *testing.T) {
+	key := Key{ID: "hs", Algorithm: HS256, Material: []byte("secret")}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	signer := NewSigner(key, Options{Issuer: "netgo", Audience: []string{"api"}, TTL: time.Minute})
+	signer.now = func() time.Time { return now }
+
+	cases := []struct {
+		claims map[string]any
+		at     time.Time
+		err    error
+	}{
+		{nil, now, nil},
+		{nil, now.Add(80 * time.Second), nil}, // within the leeway
+		{nil, now.Add(2 * time.Minute), ErrExpired},
+		{map[string]any{"nbf": now.Add(time.Minute).Unix()}, now, ErrNotYetValid},
+		{map[string]any{"nbf": now.Add(20 * time.Second).Unix()}, now, nil},
+		{map[string]any{"iss": "someone"}, now, ErrIssuer},
+		{map[string]any{"aud": "web"}, now, ErrAudience},
+		{map[string]any{"aud": []string{"web", "api"}}, now, nil},
+	}
+
+	for _, c := range cases {
+		token, _ := signer.Sign(c.claims)
+
+		verifier := NewVerifier(NewKeySet(key), Options{Issuer: "netgo", Audience: []string{"api"}})
+		verifier.now = func() time.Time { return c.at }
+
+		if _, err := verifier.Verify(token); !errors.Is(err, c.err) {
+			t.Fatalf(`Verify() of %v at %v error = %v, want %v`, c.claims, c.at.Sub(now), err, c.err)
+		}
+	}
+
+	token, _ := signer.Sign(nil)
+	strict := NewVerifier(NewKeySet(key), Options{Leeway: -1})
+	strict.now = func() time.Time { return now.Add(61 * time.Second) }
+	if _, err := strict.Verify(token); !errors.Is(err, ErrExpired) {
+		t.Fatalf(`Verify() without leeway error = %v, want ErrExpired`, err)
+	}
+}
@@ -0,0 +1,386 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// signing algorithm constants
+const (
+	HS256 = "HS256" // HMAC with SHA-256, the same secret signs and verifies
+	RS256 = "RS256" // RSA PKCS #1 v1.5 with SHA-256
+	ES256 = "ES256" // ECDSA on P-256 with SHA-256
+	EDDSA = "EdDSA" // Ed25519
+)
+
+var ErrKeyType = errors.New("jwt: the key material does not fit the algorithm")
+
+type Key struct {
+	// the kid of the tokens signed with the key
+	ID        string
+	Algorithm string
+	// []byte for HS256, *rsa.PrivateKey or *rsa.PublicKey for RS256, *ecdsa.PrivateKey or
+	// *ecdsa.PublicKey for ES256, ed25519.PrivateKey or ed25519.PublicKey for EdDSA.
+	// Public keys can only verify
+	Material any
+}
+
+// keys by id, in the order they were added
+type KeySet struct {
+	keys []Key
+}
+
+// a JSON Web Key as found in a JWKS file (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	// oct
+	K string `json:"k,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	P string `json:"p,omitempty"`
+	Q string `json:"q,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// the private part of RSA, EC and OKP keys
+	D string `json:"d,omitempty"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// Public: creates a key set holding the keys
+func NewKeySet(keys ...Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// Public: loads the keys of a local JWKS file, keys meant for encryption are skipped
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
+
+// Public: parses the keys of a JWKS document
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var document jwks
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("jwt: parsing the JWKS: %w", err)
+	}
+
+	set := NewKeySet()
+	for i, k := range document.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		key, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %d (%q) of the JWKS: %w", i, k.Kid, err)
+		}
+
+		set.Add(key)
+	}
+
+	return set, nil
+}
+
+func (s *KeySet) Add(key Key) *KeySet {
+	s.keys = append(s.keys, key)
+	return s
+}
+
+// returns the key with the id
+func (s *KeySet) Find(id string) (Key, bool) {
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return Key{}, false
+}
+
+func (s *KeySet) Keys() []Key {
+	return s.keys
+}
+
+// encodes the keys as a JWKS document. Without the private parts it can be published for clients
+// to verify tokens, HS256 secrets are left out then
+func (s *KeySet) JWKS(private bool) ([]byte, error) {
+	document := jwks{Keys: []jwk{}}
+	for _, key := range s.keys {
+		if key.Algorithm == HS256 && !private {
+			continue
+		}
+
+		k, err := toJWK(key, private)
+		if err != nil {
+			return nil, err
+		}
+
+		document.Keys = append(document.Keys, k)
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func (k jwk) key() (Key, error) {
+	key := Key{ID: k.Kid, Algorithm: k.Alg}
+
+	switch k.Kty {
+	case "oct":
+		secret, err := decodeSegment(k.K)
+		if err != nil {
+			return key, err
+		}
+		key.Material = secret
+		key.Algorithm = orDefault(key.Algorithm, HS256)
+	case "RSA":
+		public := &rsa.PublicKey{N: decodeInt(k.N), E: int(decodeInt(k.E).Int64())}
+		key.Material = public
+		key.Algorithm = orDefault(key.Algorithm, RS256)
+
+		if k.D != "" {
+			private := &rsa.PrivateKey{
+				PublicKey: *public,
+				D:         decodeInt(k.D),
+				Primes:    []*big.Int{decodeInt(k.P), decodeInt(k.Q)},
+			}
+			if err := private.Validate(); err != nil {
+				return key, err
+			}
+			private.Precompute()
+			key.Material = private
+		}
+	case "EC":
+		if k.Crv != "P-256" {
+			return key, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: decodeInt(k.X), Y: decodeInt(k.Y)}
+		key.Material = public
+		key.Algorithm = orDefault(key.Algorithm, ES256)
+
+		if k.D != "" {
+			key.Material = &ecdsa.PrivateKey{PublicKey: *public, D: decodeInt(k.D)}
+		}
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return key, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		public, err := decodeSegment(k.X)
+		if err != nil || len(public) != ed25519.PublicKeySize {
+			return key, ErrKeyType
+		}
+		key.Material = ed25519.PublicKey(public)
+		key.Algorithm = orDefault(key.Algorithm, EDDSA)
+
+		if k.D != "" {
+			seed, err := decodeSegment(k.D)
+			if err != nil || len(seed) != ed25519.SeedSize {
+				return key, ErrKeyType
+			}
+			key.Material = ed25519.NewKeyFromSeed(seed)
+		}
+	default:
+		return key, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	return key, nil
+}
+
+func toJWK(key Key, private bool) (jwk, error) {
+	k := jwk{Kid: key.ID, Alg: key.Algorithm, Use: "sig"}
+
+	switch material := key.Material.(type) {
+	case []byte:
+		k.Kty, k.K = "oct", encodeSegment(material)
+	case *rsa.PrivateKey:
+		k = rsaJWK(k, &material.PublicKey)
+		if private && len(material.Primes) == 2 {
+			k.D, k.P, k.Q = encodeInt(material.D, 0), encodeInt(material.Primes[0], 0), encodeInt(material.Primes[1], 0)
+		}
+	case *rsa.PublicKey:
+		k = rsaJWK(k, material)
+	case *ecdsa.PrivateKey:
+		k = ecJWK(k, &material.PublicKey)
+		if private {
+			k.D = encodeInt(material.D, 32)
+		}
+	case *ecdsa.PublicKey:
+		k = ecJWK(k, material)
+	case ed25519.PrivateKey:
+		k.Kty, k.Crv, k.X = "OKP", "Ed25519", encodeSegment(material.Public().(ed25519.PublicKey))
+		if private {
+			k.D = encodeSegment(material.Seed())
+		}
+	case ed25519.PublicKey:
+		k.Kty, k.Crv, k.X = "OKP", "Ed25519", encodeSegment(material)
+	default:
+		return k, ErrKeyType
+	}
+
+	return k, nil
+}
+
+func rsaJWK(k jwk, public *rsa.PublicKey) jwk {
+	k.Kty, k.N, k.E = "RSA", encodeInt(public.N, 0), encodeInt(big.NewInt(int64(public.E)), 0)
+	return k
+}
+
+func ecJWK(k jwk, public *ecdsa.PublicKey) jwk {
+	k.Kty, k.Crv, k.X, k.Y = "EC", "P-256", encodeInt(public.X, 32), encodeInt(public.Y, 32)
+	return k
+}
+
+// signs the input of a token with the key
+func (k Key) sign(input []byte) ([]byte, error) {
+	hash := sha256.Sum256(input)
+
+	switch k.Algorithm {
+	case HS256:
+		secret, ok := k.Material.([]byte)
+		if !ok {
+			return nil, ErrKeyType
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		private, ok := k.Material.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrKeyType
+		}
+		return rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, hash[:])
+	case ES256:
+		private, ok := k.Material.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, ErrKeyType
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, private, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed size concatenation of r and s, not ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	case EDDSA:
+		private, ok := k.Material.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrKeyType
+		}
+		return ed25519.Sign(private, input), nil
+	}
+
+	return nil, fmt.Errorf("jwt: unsupported algorithm %q", k.Algorithm)
+}
+
+// reports whether the signature of the input was made with the key
+func (k Key) verify(input []byte, signature []byte) bool {
+	hash := sha256.Sum256(input)
+
+	switch k.Algorithm {
+	case HS256:
+		secret, ok := k.Material.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case RS256:
+		var public *rsa.PublicKey
+		switch material := k.Material.(type) {
+		case *rsa.PrivateKey:
+			public = &material.PublicKey
+		case *rsa.PublicKey:
+			public = material
+		default:
+			return false
+		}
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, hash[:], signature) == nil
+	case ES256:
+		var public *ecdsa.PublicKey
+		switch material := k.Material.(type) {
+		case *ecdsa.PrivateKey:
+			public = &material.PublicKey
+		case *ecdsa.PublicKey:
+			public = material
+		default:
+			return false
+		}
+		if len(signature) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(public, hash[:], r, s)
+	case EDDSA:
+		var public ed25519.PublicKey
+		switch material := k.Material.(type) {
+		case ed25519.PrivateKey:
+			public = material.Public().(ed25519.PublicKey)
+		case ed25519.PublicKey:
+			public = material
+		default:
+			return false
+		}
+		return len(public) == ed25519.PublicKeySize && ed25519.Verify(public, input, signature)
+	}
+
+	return false
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
+
+// big-endian bytes of the number, left padded to size when given
+func encodeInt(n *big.Int, size int) string {
+	if size > 0 {
+		return encodeSegment(n.FillBytes(make([]byte, size)))
+	}
+
+	return encodeSegment(n.Bytes())
+}
+
+func decodeInt(segment string) *big.Int {
+	data, _ := decodeSegment(segment)
+	return new(big.Int).SetBytes(data)
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJWKSShouldRoundTripKeys(t *testing.T) {
+	keys := testKeys(t)
+
+	private, err := NewKeySet(keys...).JWKS(true)
+	if err != nil {
+		t.Fatalf(`JWKS(true) error = %v, want nil`, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, private, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf(`LoadJWKS() error = %v, want nil`, err)
+	}
+
+	public, _ := NewKeySet(keys...).JWKS(false)
+	if strings.Contains(string(public), `"d"`) || strings.Contains(string(public), `"oct"`) {
+		t.Fatalf(`JWKS(false) = %s, want no private parts`, public)
+	}
+
+	publicSet, err := ParseJWKS(public)
+	if err != nil {
+		t.Fatalf(`ParseJWKS() error = %v, want nil`, err)
+	}
+
+	for _, original := range keys {
+		key, ok := loaded.Find(original.ID)
+		if !ok || key.Algorithm != original.Algorithm {
+			t.Fatalf(`Find(%q) = %+v, want the loaded key`, original.ID, key)
+		}
+
+		// tokens signed by the loaded key verify with the original one, and with the published keys
+		token, err := NewSigner(key, Options{}).Sign(map[string]any{"sub": "42"})
+		if err != nil {
+			t.Fatalf(`%s: Sign() with the loaded key error = %v, want nil`, original.ID, err)
+		}
+
+		if _, err := NewVerifier(NewKeySet(original), Options{}).Verify(token); err != nil {
+			t.Fatalf(`%s: Verify() with the original key error = %v, want nil`, original.ID, err)
+		}
+
+		if original.Algorithm == HS256 {
+			continue
+		}
+
+		if _, err := NewVerifier(publicSet, Options{}).Verify(token); err != nil {
+			t.Fatalf(`%s: Verify() with the published key error = %v, want nil`, original.ID, err)
+		}
+
+		published, _ := publicSet.Find(original.ID)
+		if _, err := NewSigner(published, Options{}).Sign(nil); err != ErrKeyType {
+			t.Fatalf(`%s: Sign() with a public key error = %v, want ErrKeyType`, original.ID, err)
+		}
+	}
+}
+
+func TestParseJWKSShouldReportInvalidKeys(t *testing.T) {
+	documents := []string{
+		`not json`,
+		`{"keys":[{"kty":"EC","crv":"P-384","x":"AA","y":"AA"}]}`,
+		`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"AA"}]}`,
+		`{"keys":[{"kty":"unknown"}]}`,
+	}
+
+	for _, document := range documents {
+		if _, err := ParseJWKS([]byte(document)); err == nil {
+			t.Fatalf(`ParseJWKS(%s) error = nil, want an error`, document)
+		}
+	}
+
+	set, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","use":"enc","k":"AA"},{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`))
+	if err != nil || len(set.Keys()) != 1 || string(set.Keys()[0].Material.([]byte)) != "secret" {
+		t.Fatalf(`ParseJWKS() = %+v, %v, want only the signing key`, set, err)
+	}
+}
@@ -0,0 +1,189 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const DEFAULT_REFRESH_TTL = 30 * 24 * time.Hour
+
+// how often the memory store forgets expired refresh tokens
+const SWEEP_INTERVAL = time.Minute
+
+var (
+	ErrRefreshUnknown = errors.New("jwt: unknown refresh token")
+	ErrRefreshExpired = errors.New("jwt: refresh token expired")
+	// a refresh token was used twice, it was most likely stolen. Every token of its family is revoked
+	ErrRefreshReused = errors.New("jwt: refresh token reused")
+)
+
+// a refresh token as kept by a store, only the hash of the token itself is kept
+type RefreshToken struct {
+	Hash string
+	// every token obtained by rotating the same initial token shares its family
+	Family  string
+	Subject string
+	Expires time.Time
+	Used    bool
+}
+
+// keeps refresh tokens, implement it to keep them in a database
+type RefreshStore interface {
+	Save(token *RefreshToken) error
+	// marks the token as used and returns it as it was before, nil when it is unknown. Has to be
+	// atomic so that two concurrent uses of a token can not both see it unused
+	Use(hash string) (*RefreshToken, error)
+	RevokeFamily(family string) error
+}
+
+// issues refresh tokens and rotates them: every token can be used once and is exchanged for a new one
+type Refresher struct {
+	store RefreshStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// Public: creates a refresher keeping the tokens in the store, tokens are valid for ttl
+// (DEFAULT_REFRESH_TTL when zero)
+func NewRefresher(store RefreshStore, ttl time.Duration) *Refresher {
+	if ttl <= 0 {
+		ttl = DEFAULT_REFRESH_TTL
+	}
+
+	return &Refresher{store: store, ttl: ttl, now: time.Now}
+}
+
+// issues the first refresh token of the subject, e.g. on login
+func (r *Refresher) Issue(subject string) (string, error) {
+	return r.issue(subject, randomID())
+}
+
+// exchanges a refresh token for a new one, returns the new token and the subject to issue an
+// access token for
+func (r *Refresher) Rotate(token string) (string, string, error) {
+	used, err := r.store.Use(hashToken(token))
+	if err != nil {
+		return "", "", err
+	}
+
+	if used == nil {
+		return "", "", ErrRefreshUnknown
+	}
+
+	if used.Used {
+		if err := r.store.RevokeFamily(used.Family); err != nil {
+			return "", "", err
+		}
+		return "", "", ErrRefreshReused
+	}
+
+	if !r.now().Before(used.Expires) {
+		return "", "", ErrRefreshExpired
+	}
+
+	next, err := r.issue(used.Subject, used.Family)
+	if err != nil {
+		return "", "", err
+	}
+
+	return next, used.Subject, nil
+}
+
+// revokes the token and every token of its family, e.g. on logout
+func (r *Refresher) Revoke(token string) error {
+	used, err := r.store.Use(hashToken(token))
+	if err != nil || used == nil {
+		return err
+	}
+
+	return r.store.RevokeFamily(used.Family)
+}
+
+func (r *Refresher) issue(subject string, family string) (string, error) {
+	token := randomID() + randomID()
+
+	err := r.store.Save(&RefreshToken{
+		Hash:    hashToken(token),
+		Family:  family,
+		Subject: subject,
+		Expires: r.now().Add(r.ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// keeps refresh tokens in memory, they are lost on restart
+type MemoryRefreshStore struct {
+	mu        sync.Mutex
+	tokens    map[string]*RefreshToken
+	lastSweep time.Time
+}
+
+// Public: creates an empty MemoryRefreshStore
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{tokens: make(map[string]*RefreshToken), lastSweep: time.Now()}
+}
+
+func (s *MemoryRefreshStore) Save(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.lastSweep) >= SWEEP_INTERVAL {
+		s.sweep(now)
+	}
+
+	stored := *token
+	s.tokens[token.Hash] = &stored
+
+	return nil
+}
+
+func (s *MemoryRefreshStore) Use(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[hash]
+	if !ok {
+		return nil, nil
+	}
+
+	before := *stored
+	stored.Used = true
+
+	return &before, nil
+}
+
+func (s *MemoryRefreshStore) RevokeFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, t := range s.tokens {
+		if t.Family == family {
+			delete(s.tokens, hash)
+		}
+	}
+
+	return nil
+}
+
+// expired tokens are of no use anymore, not even to detect a reuse
+func (s *MemoryRefreshStore) sweep(now time.Time) {
+	for hash, t := range s.tokens {
+		if !now.Before(t.Expires) {
+			delete(s.tokens, hash)
+		}
+	}
+
+	s.lastSweep = now
+}
@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokensShouldRotate(t *testing.T) {
+	refresher := NewRefresher(NewMemoryRefreshStore(), time.Hour)
+
+	first, err := refresher.Issue("42")
+	if err != nil {
+		t.Fatalf(`Issue() error = %v, want nil`, err)
+	}
+
+	second, subject, err := refresher.Rotate(first)
+	if err != nil || subject != "42" || second == first {
+		t.Fatalf(`Rotate() = %q, %q, %v, want a new token for 42`, second, subject, err)
+	}
+
+	third, _, err := refresher.Rotate(second)
+	if err != nil {
+		t.Fatalf(`Rotate() of the new token error = %v, want nil`, err)
+	}
+
+	// using the first token again gives the theft away, the whole family is revoked
+	if _, _, err := refresher.Rotate(first); !errors.Is(err, ErrRefreshReused) {
+		t.Fatalf(`Rotate() of a used token error = %v, want ErrRefreshReused`, err)
+	}
+
+	if _, _, err := refresher.Rotate(third); !errors.Is(err, ErrRefreshUnknown) {
+		t.Fatalf(`Rotate() after the reuse error = %v, want ErrRefreshUnknown`, err)
+	}
+
+	if _, _, err := refresher.Rotate("forged"); !errors.Is(err, ErrRefreshUnknown) {
+		t.Fatalf(`Rotate() of a forged token error = %v, want ErrRefreshUnknown`, err)
+	}
+}
+
+func TestRefreshTokensShouldExpireAndBeRevoked(t *testing.T) {
+	refresher := NewRefresher(NewMemoryRefreshStore(), time.Hour)
+
+	token, _ := refresher.Issue("42")
+	refresher.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := refresher.Rotate(token); !errors.Is(err, ErrRefreshExpired) {
+		t.Fatalf(`Rotate() of an expired token error = %v, want ErrRefreshExpired`, err)
+	}
+
+	refresher.now = time.Now
+	token, _ = refresher.Issue("42")
+	if err := refresher.Revoke(token); err != nil {
+		t.Fatalf(`Revoke() error = %v, want nil`, err)
+	}
+
+	if _, _, err := refresher.Rotate(token); !errors.Is(err, ErrRefreshUnknown) {
+		t.Fatalf(`Rotate() of a revoked token error = %v, want ErrRefreshUnknown`, err)
+	}
+}
+
+func TestMemoryRefreshStoreShouldSweepOnAnInterval(t *testing.T) {
+	store := NewMemoryRefreshStore()
+	store.Save(&RefreshToken{Hash: "expired", Expires: time.Now().Add(-time.Second)})
+	store.Save(&RefreshToken{Hash: "alive", Expires: time.Now().Add(time.Hour)})
+
+	if len(store.tokens) != 2 {
+		t.Fatalf(`tokens before a sweep is due = %d, want 2`, len(store.tokens))
+	}
+
+	store.lastSweep = time.Time{}
+	store.Save(&RefreshToken{Hash: "next", Expires: time.Now().Add(time.Hour)})
+
+	if _, ok := store.tokens["expired"]; ok || len(store.tokens) != 2 {
+		t.Fatalf(`tokens after a sweep = %v, want alive and next`, store.tokens)
+	}
+}
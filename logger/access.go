@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waponix/netgo/utils/collections"
+	"github.com/waponix/netgo/utils/httpUtil"
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+// access log format constants
+const (
+	ACCESS_COMMON   = "COMMON"   // Apache Common Log Format
+	ACCESS_COMBINED = "COMBINED" // Common plus referer and user agent
+	ACCESS_JSON     = "JSON"
+)
+
+const ACCESS_DATETIME_FORMAT = "02/Jan/2006:15:04:05 -0700"
+
+type AccessLogOptions struct {
+	Format string
+	// requests to these paths are not logged, e.g. health checks
+	SkipPaths []string
+	// optional, return true to skip logging the request
+	Skip func(*http.Request) bool
+	// read the client IP from X-Forwarded-For / X-Real-IP, only enable behind a trusted proxy
+	TrustProxy bool
+	// resolves the authenticated user once the handler ran, e.g. auth.UserID. It gets the request
+	// last handed over with TrackRequest() by the middlewares running after this one. The user of
+	// HTTP basic auth is logged when it is not set or returns an empty string
+	UserID func(*http.Request) string
+}
+
+// the request as changed by the middlewares running after the access log
+type accessState struct {
+	request *http.Request
+}
+
+type accessEntry struct {
+	Time      string  `json:"time"`
+	RequestID string  `json:"requestId,omitempty"`
+	RemoteIP  string  `json:"remoteIp"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Bytes     int     `json:"bytes"`
+	Duration  float64 `json:"durationMs"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"userAgent,omitempty"`
+}
+
+// public: middleware writing one access log line per request, the lines are written as is
+// (the Template is not applied) and only when INFO is part of the LogLevels
+func AccessLog(l *Log, options AccessLogOptions) func(http.Handler) http.Handler {
+	if options.Format == "" {
+		options.Format = ACCESS_COMMON
+	}
+
+	skipPaths := collections.NewSet(options.SkipPaths...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipPaths.Has(r.URL.Path) || (options.Skip != nil && options.Skip(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			recorder := &responseRecorder{ResponseWriter: w}
+
+			state := &accessState{request: r}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessLogKey, state)))
+
+			if !sliceUtil.Contains(l.LogLevels, INFO) {
+				return
+			}
+
+			l.writeLog(formatAccess(options.Format, accessEntry{
+				Time:      start.Format(ACCESS_DATETIME_FORMAT),
+				RequestID: RequestID(r),
+				RemoteIP:  httpUtil.ClientIP(r, options.TrustProxy),
+				User:      requestUser(state.request, options.UserID),
+				Method:    r.Method,
+				Path:      r.URL.RequestURI(),
+				Proto:     r.Proto,
+				Status:    recorder.Status(),
+				Bytes:     recorder.bytes,
+				Duration:  float64(time.Since(start).Microseconds()) / 1000,
+				Referer:   r.Referer(),
+				UserAgent: r.UserAgent(),
+			}))
+		})
+	}
+}
+
+func formatAccess(format string, e accessEntry) string {
+	if format == ACCESS_JSON {
+		line, _ := json.Marshal(e)
+		return string(line)
+	}
+
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.Itoa(e.Bytes)
+	}
+
+	line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		e.RemoteIP, dash(escape(e.User)), e.Time, escape(e.Method), escape(e.Path), escape(e.Proto), e.Status, bytes)
+
+	if format == ACCESS_COMBINED {
+		line += fmt.Sprintf(` "%s" "%s"`, dash(escape(e.Referer)), dash(escape(e.UserAgent)))
+	}
+
+	return line
+}
+
+// public: hands the access log the request a middleware passes on, e.g. with the authenticated user
+// attached, so that AccessLogOptions.UserID can find the user. Does nothing outside of AccessLog()
+func TrackRequest(r *http.Request) {
+	if state, ok := r.Context().Value(accessLogKey).(*accessState); ok {
+		state.request = r
+	}
+}
+
+func requestUser(r *http.Request, userID func(*http.Request) string) string {
+	if userID != nil {
+		if id := userID(r); id != "" {
+			return id
+		}
+	}
+
+	if username, _, ok := r.BasicAuth(); ok {
+		return username
+	}
+
+	return ""
+}
+
+// escapes what the client sent like Apache does, so that it can not end a quoted field or the line:
+// " and \ get a backslash, control characters and bytes outside of ASCII become \xHH
+func escape(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&escaped, `\x%02x`, c)
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+
+	return escaped.String()
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// wraps the http.ResponseWriter to capture the status code and the size of the body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+func (rr *responseRecorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+
+	return rr.status
+}
+
+func (rr *responseRecorder) Flush() {
+	if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := rr.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+
+	return nil, nil, errors.New("logger: the response writer does not support hijacking")
+}
+
+// lets http.ResponseController reach the original writer
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
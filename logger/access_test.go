@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func serveAccessLog(l *Log, options AccessLogOptions, path string) {
+	handler := AccessLog(l, options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		want   *regexp.Regexp
+	}{
+		{ACCESS_COMMON, regexp.MustCompile(`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /product\?id=1 HTTP/1\.1" 201 5$`)},
+		{ACCESS_COMBINED, regexp.MustCompile(`^10\.0\.0\.1 - - \[.+\] "POST /product\?id=1 HTTP/1\.1" 201 5 "-" "test-agent"$`)},
+	}
+
+	for _, c := range cases {
+		l := New()
+		l.Filename = "test_access.log"
+
+		serveAccessLog(l, AccessLogOptions{Format: c.format}, "/product?id=1")
+
+		lastLine, err := ReadLastLine(l.Filename)
+		os.Remove(l.Filename)
+
+		if !c.want.MatchString(lastLine) || err != nil {
+			t.Fatalf(`%s access log = %q, %v, want match for %#q, nil`, c.format, lastLine, err, c.want)
+		}
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	l := New()
+	l.Filename = "test_access.log"
+
+	defer os.Remove(l.Filename)
+
+	serveAccessLog(l, AccessLogOptions{Format: ACCESS_JSON}, "/product")
+
+	lastLine, _ := ReadLastLine(l.Filename)
+
+	var e accessEntry
+	if err := json.Unmarshal([]byte(lastLine), &e); err != nil {
+		t.Fatalf(`json access log = %q is not valid json: %v`, lastLine, err)
+	}
+
+	if e.Status != http.StatusCreated || e.Bytes != 5 || e.Method != http.MethodPost || e.UserAgent != "test-agent" {
+		t.Fatalf(`json access log = %+v, want status 201, 5 bytes, POST and the user agent`, e)
+	}
+}
+
+func TestAccessLogShouldSkipPaths(t *testing.T) {
+	l := New()
+	l.Filename = "test_access.log"
+
+	defer os.Remove(l.Filename)
+
+	serveAccessLog(l, AccessLogOptions{SkipPaths: []string{"/health"}}, "/health")
+
+	if fileExists(l.Filename) {
+		t.Fatalf("Access log should not be written for skipped paths")
+	}
+}
+
+func TestAccessLogShouldEscapeClientValues(t *testing.T) {
+	l := New()
+	l.Filename = "test_access.log"
+
+	defer os.Remove(l.Filename)
+
+	handler := AccessLog(l, AccessLogOptions{Format: ACCESS_COMBINED})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Referer", `x" 200 5 "forged`)
+	req.Header.Set("User-Agent", "agent\\\x01\xff")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	want := regexp.MustCompile(`^10\.0\.0\.1 - - \[.+\] "GET / HTTP/1\.1" 200 - "x\\" 200 5 \\"forged" "agent\\\\\\x01\\xff"$`)
+
+	if !want.MatchString(lastLine) {
+		t.Fatalf(`access log of quoted headers = %q, want match for %#q`, lastLine, want)
+	}
+}
+
+type testUserKey struct{}
+
+func TestAccessLogShouldResolveTheUserAfterTheHandler(t *testing.T) {
+	l := New()
+	l.Filename = "test_access.log"
+
+	defer os.Remove(l.Filename)
+
+	userID := func(r *http.Request) string {
+		id, _ := r.Context().Value(testUserKey{}).(string)
+		return id
+	}
+
+	// stands for an authentication middleware running after the access log
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), testUserKey{}, "42"))
+			TrackRequest(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handler := AccessLog(l, AccessLogOptions{UserID: userID})(authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product", nil))
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	if !strings.Contains(lastLine, " - 42 [") {
+		t.Fatalf(`access log = %q, want the user 42`, lastLine)
+	}
+}
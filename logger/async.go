@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// overflow policy constants, decides what happens when the async queue is full
+const (
+	BLOCK       = "BLOCK"       // wait until the worker frees a slot
+	DROP_OLDEST = "DROP_OLDEST" // discard the oldest queued record to make room
+	DROP_NEWEST = "DROP_NEWEST" // discard the record being logged
+	SAMPLE      = "SAMPLE"      // keep every SampleRate-th overflowing record, discard the rest
+)
+
+const DEFAULT_QUEUE_SIZE = 1024
+
+var ErrLoggerClosed = errors.New("logger: the async queue is already closed")
+
+type AsyncOptions struct {
+	QueueSize  int
+	Policy     string
+	SampleRate int
+	// called from the worker when a queued record could not be written
+	OnError func(error)
+}
+
+type record struct {
+	filename string
+	line     string
+}
+
+type asyncWriter struct {
+	options  AsyncOptions
+	queue    chan record
+	mutex    sync.RWMutex
+	closed   bool
+	done     chan struct{}
+	dropped  atomic.Uint64
+	overflow atomic.Uint64
+}
+
+// public: switch the logger to non-blocking mode, records are queued and written by a background worker.
+// Call Close() on shutdown to make sure every queued record reaches the file.
+func (l *Log) Async(options AsyncOptions) *Log {
+	if l.async != nil {
+		return l
+	}
+
+	if options.QueueSize <= 0 {
+		options.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+
+	if options.Policy == "" {
+		options.Policy = BLOCK
+	}
+
+	if options.SampleRate <= 0 {
+		options.SampleRate = 10
+	}
+
+	l.async = &asyncWriter{
+		options: options,
+		queue:   make(chan record, options.QueueSize),
+		done:    make(chan struct{}),
+	}
+
+	go l.async.work()
+
+	return l
+}
+
+// public: stop accepting records and wait until the queue is drained, on a synchronous logger it only
+// flushes the pending duplicate summary
+func (l *Log) Close() error {
+	// write out the summary of a pending run of duplicates before shutting down
+	l.flushDuplicates()
+
+	if l.async == nil {
+		return nil
+	}
+
+	return l.async.close()
+}
+
+// public: number of records discarded by the overflow policy
+func (l *Log) Dropped() uint64 {
+	if l.async == nil {
+		return 0
+	}
+
+	return l.async.dropped.Load()
+}
+
+func (a *asyncWriter) enqueue(rec record) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.closed {
+		return ErrLoggerClosed
+	}
+
+	switch a.options.Policy {
+	case DROP_NEWEST:
+		select {
+		case a.queue <- rec:
+		default:
+			a.dropped.Add(1)
+		}
+	case DROP_OLDEST:
+		for {
+			select {
+			case a.queue <- rec:
+				return nil
+			default:
+			}
+
+			// make room by discarding the record at the head of the queue
+			select {
+			case <-a.queue:
+				a.dropped.Add(1)
+			default:
+			}
+		}
+	case SAMPLE:
+		select {
+		case a.queue <- rec:
+		default:
+			if a.overflow.Add(1)%uint64(a.options.SampleRate) == 0 {
+				a.queue <- rec
+			} else {
+				a.dropped.Add(1)
+			}
+		}
+	default:
+		a.queue <- rec
+	}
+
+	return nil
+}
+
+// drains the queue until it gets closed
+func (a *asyncWriter) work() {
+	defer close(a.done)
+
+	for rec := range a.queue {
+		err := writeFile(rec.filename, rec.line)
+		if err != nil && a.options.OnError != nil {
+			a.options.OnError(err)
+		}
+	}
+}
+
+func (a *asyncWriter) close() error {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return ErrLoggerClosed
+	}
+	a.closed = true
+	close(a.queue)
+	a.mutex.Unlock()
+
+	// wait for the worker to write everything that is still queued
+	<-a.done
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bufio"
+	"os"
+	"testing"
+)
+
+func TestAsyncShouldDrainOnClose(t *testing.T) {
+	l := New().Async(AsyncOptions{QueueSize: 4})
+	l.Filename = "test_async.log"
+
+	defer os.Remove(l.Filename)
+
+	for i := 0; i < 100; i++ {
+		l.Info("This is an async info log")
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf(`l.Close() = %v, want nil`, err)
+	}
+
+	lines, err := countLines(l.Filename)
+	if lines != 100 || err != nil {
+		t.Fatalf(`countLines(%q) = %d, %v, want 100, nil`, l.Filename, lines, err)
+	}
+}
+
+func TestAsyncShouldRejectAfterClose(t *testing.T) {
+	l := New().Async(AsyncOptions{})
+	l.Filename = "test_async.log"
+
+	defer os.Remove(l.Filename)
+
+	l.Close()
+
+	if err := l.Info("This is an async info log"); err != ErrLoggerClosed {
+		t.Fatalf(`l.Info() after Close() = %v, want %v`, err, ErrLoggerClosed)
+	}
+}
+
+func TestAsyncOverflowPolicies(t *testing.T) {
+	cases := []struct {
+		policy  string
+		dropped uint64
+		head    string
+	}{
+		{DROP_NEWEST, 3, "0"},
+		{DROP_OLDEST, 3, "3"},
+	}
+
+	for _, c := range cases {
+		// no worker is started so the queue stays full
+		a := &asyncWriter{
+			options: AsyncOptions{Policy: c.policy, SampleRate: 3},
+			queue:   make(chan record, 2),
+		}
+
+		for _, line := range []string{"0", "1", "2", "3", "4"} {
+			a.enqueue(record{line: line})
+		}
+
+		if a.dropped.Load() != c.dropped {
+			t.Fatalf(`%s dropped = %d, want %d`, c.policy, a.dropped.Load(), c.dropped)
+		}
+
+		if head := (<-a.queue).line; head != c.head {
+			t.Fatalf(`%s head of queue = %q, want %q`, c.policy, head, c.head)
+		}
+	}
+}
+
+func TestAsyncSamplePolicyKeepsEveryNth(t *testing.T) {
+	a := &asyncWriter{
+		options: AsyncOptions{Policy: SAMPLE, SampleRate: 3},
+		queue:   make(chan record, 2),
+	}
+
+	for _, line := range []string{"0", "1", "2", "3"} {
+		a.enqueue(record{line: line})
+	}
+
+	// the third overflowing record is kept and waits for a free slot
+	go func() { <-a.queue }()
+	a.enqueue(record{line: "4"})
+
+	if a.dropped.Load() != 2 {
+		t.Fatalf(`SAMPLE dropped = %d, want 2`, a.dropped.Load())
+	}
+
+	if tail := []string{(<-a.queue).line, (<-a.queue).line}; tail[1] != "4" {
+		t.Fatalf(`SAMPLE queue = %v, want the sampled record "4" last`, tail)
+	}
+}
+
+func countLines(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+
+	return lines, scanner.Err()
+}
@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// template tokens, anything outside of a token is written as is
+const (
+	TOKEN_TIME             = "{time}"             // time formatted with Log.TimeFormat
+	TOKEN_TIME_RFC3339     = "{time:rfc3339}"     // 2006-01-02T15:04:05Z07:00
+	TOKEN_TIME_RFC3339NANO = "{time:rfc3339nano}" // 2006-01-02T15:04:05.999999999Z07:00
+	TOKEN_TIME_UNIXNANO    = "{time:unixnano}"    // nanoseconds since the unix epoch
+	TOKEN_TIME_ZONE        = "{time:zone}"        // timezone abbreviation e.g. UTC
+	TOKEN_LEVEL            = "{level}"
+	TOKEN_CALLER           = "{caller}"      // file.go:12
+	TOKEN_CALLER_LONG      = "{caller:long}" // /absolute/path/to/file.go:12
+	TOKEN_FUNC             = "{func}"        // package.Function
+	TOKEN_GOROUTINE        = "{goroutine}"   // id of the calling goroutine
+	TOKEN_FIELDS           = "{fields}"      // key=value pairs added through With()
+	TOKEN_MESSAGE          = "{message}"
+)
+
+// ANSI colour codes used for the level when Log.Color is on
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorPurple = "\033[35m"
+)
+
+var levelColors = map[string]string{
+	INFO:   colorGreen,
+	DEBUG:  colorBlue,
+	NOTICE: colorYellow,
+	ERROR:  colorRed,
+	FATAL:  colorPurple,
+}
+
+type callerInfo struct {
+	file     string
+	line     int
+	function string
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// everything known about a single log line before it gets rendered
+type entry struct {
+	time    time.Time
+	level   string
+	message string
+	caller  callerInfo
+}
+
+// a compiled template is a list of literal text and tokens
+type segment struct {
+	literal string
+	token   string
+}
+
+var knownTokens = map[string]bool{
+	TOKEN_TIME:             true,
+	TOKEN_TIME_RFC3339:     true,
+	TOKEN_TIME_RFC3339NANO: true,
+	TOKEN_TIME_UNIXNANO:    true,
+	TOKEN_TIME_ZONE:        true,
+	TOKEN_LEVEL:            true,
+	TOKEN_CALLER:           true,
+	TOKEN_CALLER_LONG:      true,
+	TOKEN_FUNC:             true,
+	TOKEN_GOROUTINE:        true,
+	TOKEN_FIELDS:           true,
+	TOKEN_MESSAGE:          true,
+}
+
+// compiled templates, keyed by the template string
+var templates sync.Map
+
+func compileTemplate(template string) []segment {
+	if compiled, ok := templates.Load(template); ok {
+		return compiled.([]segment)
+	}
+
+	var segments []segment
+	var literal strings.Builder
+	rest := template
+
+	// only known tokens are replaced, other braces like the ones of a JSON template are kept as is
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			literal.WriteString(rest)
+			break
+		}
+
+		literal.WriteString(rest[:start])
+		rest = rest[start:]
+
+		end := strings.IndexByte(rest, '}')
+		if end < 0 || !knownTokens[rest[:end+1]] {
+			literal.WriteByte('{')
+			rest = rest[1:]
+			continue
+		}
+
+		if literal.Len() > 0 {
+			segments = append(segments, segment{literal: literal.String()})
+			literal.Reset()
+		}
+
+		segments = append(segments, segment{token: rest[:end+1]})
+		rest = rest[end+1:]
+	}
+
+	if literal.Len() > 0 {
+		segments = append(segments, segment{literal: literal.String()})
+	}
+
+	templates.Store(template, segments)
+
+	return segments
+}
+
+func (l *Log) render(e entry) string {
+	template := l.Template
+	if template == "" {
+		template = DEFAULT_TEMPLATE
+	}
+
+	if l.Location != nil {
+		e.time = e.time.In(l.Location)
+	}
+
+	var buffer bytes.Buffer
+	// where the line ends without the spaces left behind by empty trailing tokens (e.g. no fields),
+	// spaces written by the tokens themselves are kept
+	end := 0
+
+	for _, seg := range compileTemplate(template) {
+		if seg.token == "" {
+			buffer.WriteString(seg.literal)
+			if trimmed := strings.TrimRight(seg.literal, " "); trimmed != "" {
+				end = buffer.Len() - len(seg.literal) + len(trimmed)
+			}
+			continue
+		}
+
+		if value := l.renderToken(seg.token, e); value != "" {
+			buffer.WriteString(value)
+			end = buffer.Len()
+		}
+	}
+
+	return buffer.String()[:end]
+}
+
+func (l *Log) renderToken(token string, e entry) string {
+	switch token {
+	case TOKEN_TIME:
+		timeFormat := l.TimeFormat
+		if timeFormat == "" {
+			timeFormat = DATETIME_FORMAT
+		}
+		return e.time.Format(timeFormat)
+	case TOKEN_TIME_RFC3339:
+		return e.time.Format(time.RFC3339)
+	case TOKEN_TIME_RFC3339NANO:
+		return e.time.Format(time.RFC3339Nano)
+	case TOKEN_TIME_UNIXNANO:
+		return strconv.FormatInt(e.time.UnixNano(), 10)
+	case TOKEN_TIME_ZONE:
+		zone, _ := e.time.Zone()
+		return zone
+	case TOKEN_LEVEL:
+		if l.Color {
+			if color, ok := levelColors[e.level]; ok {
+				return color + e.level + colorReset
+			}
+		}
+		return e.level
+	case TOKEN_CALLER:
+		return filepath.Base(e.caller.file) + ":" + strconv.Itoa(e.caller.line)
+	case TOKEN_CALLER_LONG:
+		return e.caller.file + ":" + strconv.Itoa(e.caller.line)
+	case TOKEN_FUNC:
+		return e.caller.function
+	case TOKEN_GOROUTINE:
+		return strconv.FormatUint(goroutineID(), 10)
+	case TOKEN_FIELDS:
+		return formatFields(l.fields)
+	case TOKEN_MESSAGE:
+		return e.message
+	}
+
+	return token
+}
+
+func formatFields(fields []field) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		value := fmt.Sprint(f.value)
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		parts[i] = f.key + "=" + value
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// reads the goroutine id from the first line of the stack trace: "goroutine 18 [running]:"
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+
+	return id
+}
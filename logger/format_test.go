@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTemplateTokens(t *testing.T) {
+	l := New()
+	l.Filename = "test_format.log"
+	l.Template = "{time:rfc3339} {time:zone} {caller} {level} {func} {goroutine}: {message}"
+	l.Location = time.UTC
+
+	defer os.Remove(l.Filename)
+
+	want := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z UTC format_test\.go:\d+ INFO \S+\.TestTemplateTokens \d+: This is an info log$`)
+
+	l.Info("This is an info log")
+
+	lastLine, err := ReadLastLine(l.Filename)
+
+	if !want.MatchString(lastLine) || err != nil {
+		t.Fatalf(`l.Info("This is an info log") = %q, %v, want match for %#q, nil`, lastLine, err, want)
+	}
+}
+
+func TestFieldsShouldRender(t *testing.T) {
+	l := New()
+	l.Filename = "test_format.log"
+	l.Template = "{level}: {message} {fields}"
+
+	defer os.Remove(l.Filename)
+
+	child := l.With("requestId", "abc").With("path", "/product 1")
+	child.Info("This is an info log")
+
+	lastLine, err := ReadLastLine(l.Filename)
+	want := `INFO: This is an info log requestId=abc path="/product 1"`
+
+	if lastLine != want || err != nil {
+		t.Fatalf(`child.Info("This is an info log") = %q, %v, want %q, nil`, lastLine, err, want)
+	}
+
+	// the parent should not pick up the fields of its child
+	l.Info("This is an info log")
+
+	lastLine, _ = ReadLastLine(l.Filename)
+	want = `INFO: This is an info log`
+
+	if lastLine != want {
+		t.Fatalf(`l.Info("This is an info log") = %q, want %q`, lastLine, want)
+	}
+}
+
+func TestColorShouldWrapLevel(t *testing.T) {
+	l := New()
+	l.Filename = "test_format.log"
+	l.Template = "{level}"
+	l.Color = true
+
+	defer os.Remove(l.Filename)
+
+	l.Error("This is an error log")
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	want := colorRed + ERROR + colorReset
+
+	if lastLine != want {
+		t.Fatalf(`l.Error() with Color = %q, want %q`, lastLine, want)
+	}
+}
+
+func TestTemplateShouldKeepLiteralBraces(t *testing.T) {
+	l := New()
+	l.Filename = "test_format.log"
+	l.Template = `{"level":"{level}","message":"{message}","user":"{user}"} {fields}`
+
+	defer os.Remove(l.Filename)
+
+	l.Info("This is an info log")
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	want := `{"level":"INFO","message":"This is an info log","user":"{user}"}`
+
+	if lastLine != want {
+		t.Fatalf(`l.Info() with a JSON template = %q, want %q`, lastLine, want)
+	}
+}
+
+func TestTemplateShouldKeepTrailingSpacesOfTheMessage(t *testing.T) {
+	l := New()
+	l.Filename = "test_format.log"
+	l.Template = "{level}: {message} {fields}"
+
+	defer os.Remove(l.Filename)
+
+	l.Info("This is an info log  ")
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	want := "INFO: This is an info log  "
+
+	if lastLine != want {
+		t.Fatalf(`l.Info("This is an info log  ") = %q, want %q`, lastLine, want)
+	}
+}
@@ -20,8 +20,16 @@ const (
 
 // format constants
 const (
-	FORMAT          = "[%s] (%d) %s.%s: %s"
-	DATETIME_FORMAT = "2006-01-02 15:04:05"
+	// Deprecated: lines are laid out with Log.Template, see DEFAULT_TEMPLATE
+	FORMAT           = "[%s] (%d) %s.%s: %s"
+	DEFAULT_TEMPLATE = "[{time}] {caller} {level}: {message} {fields}"
+	DATETIME_FORMAT  = "2006-01-02 15:04:05"
+)
+
+// special filenames that write to the process' standard streams instead of a file
+const (
+	STDOUT = "/dev/stdout"
+	STDERR = "/dev/stderr"
 )
 
 type LogInterface interface {
@@ -35,14 +43,29 @@ type LogInterface interface {
 type Log struct {
 	Filename  string
 	LogLevels []string
+	// layout of a log line, see format.go for the supported tokens
+	Template   string
+	TimeFormat string
+	// timezone used for the {time} tokens, defaults to the local timezone
+	Location *time.Location
+	// wrap the level in ANSI colours, meant for STDOUT and STDERR
+	Color  bool
+	fields []field
+	async  *asyncWriter
+	filter *filter
 }
 
 // public getter for the logger struct
 func New() *Log {
 	// set the default values for the filename
 	return &Log{
-		Filename:  "",
-		LogLevels: []string{INFO, DEBUG, NOTICE, ERROR, FATAL},
+		Filename:   "",
+		LogLevels:  []string{INFO, DEBUG, NOTICE, ERROR, FATAL},
+		Template:   DEFAULT_TEMPLATE,
+		TimeFormat: DATETIME_FORMAT,
+		// created right away so that children made with With() share it, even when Sample(),
+		// RateLimit() or Dedupe() are called after them
+		filter: newFilter(),
 	}
 }
 
@@ -50,8 +73,8 @@ func New() *Log {
 func (l *Log) Info(message string) error {
 	var err error = nil
 	// only log when log level is present in the LogLevels
-	if sliceUtil.Use(l.LogLevels).InItems(INFO) {
-		err = l.writeLog(composeLogMessage(message, INFO))
+	if sliceUtil.Contains(l.LogLevels, INFO) && l.allow(INFO, message) {
+		err = l.writeLog(l.composeLogMessage(message, INFO))
 	}
 	return err
 }
@@ -60,8 +83,8 @@ func (l *Log) Info(message string) error {
 func (l *Log) Debug(message string) error {
 	var err error = nil
 	// only log when log level is present in the LogLevels
-	if sliceUtil.Use(l.LogLevels).InItems(DEBUG) {
-		err = l.writeLog(composeLogMessage(message, DEBUG))
+	if sliceUtil.Contains(l.LogLevels, DEBUG) && l.allow(DEBUG, message) {
+		err = l.writeLog(l.composeLogMessage(message, DEBUG))
 	}
 	return err
 }
@@ -70,8 +93,8 @@ func (l *Log) Debug(message string) error {
 func (l *Log) Notice(message string) error {
 	var err error = nil
 	// only log when log level is present in the LogLevels
-	if sliceUtil.Use(l.LogLevels).InItems(NOTICE) {
-		err = l.writeLog(composeLogMessage(message, NOTICE))
+	if sliceUtil.Contains(l.LogLevels, NOTICE) && l.allow(NOTICE, message) {
+		err = l.writeLog(l.composeLogMessage(message, NOTICE))
 	}
 	return err
 }
@@ -80,8 +103,8 @@ func (l *Log) Notice(message string) error {
 func (l *Log) Error(message string) error {
 	var err error = nil
 	// only log when log level is present in the LogLevels
-	if sliceUtil.Use(l.LogLevels).InItems(ERROR) {
-		err = l.writeLog(composeLogMessage(message, ERROR))
+	if sliceUtil.Contains(l.LogLevels, ERROR) && l.allow(ERROR, message) {
+		err = l.writeLog(l.composeLogMessage(message, ERROR))
 	}
 	return err
 }
@@ -90,16 +113,53 @@ func (l *Log) Error(message string) error {
 func (l *Log) Fatal(message string) error {
 	var err error = nil
 	// only log when log level is present in the LogLevels
-	if sliceUtil.Use(l.LogLevels).InItems(FATAL) {
-		err = l.writeLog(composeLogMessage(message, FATAL))
+	if sliceUtil.Contains(l.LogLevels, FATAL) && l.allow(FATAL, message) {
+		err = l.writeLog(l.composeLogMessage(message, FATAL))
 	}
 	return err
 }
 
-// does the actual writing to the log
+// hands the line over to the async queue when enabled, otherwise writes it right away
 func (l *Log) writeLog(line string) error {
+	if l.async != nil {
+		return l.async.enqueue(record{filename: l.Filename, line: line})
+	}
+
+	return writeFile(l.Filename, line)
+}
+
+// public: returns a child logger that adds the key/value pair to the {fields} of every line,
+// the child shares the file and the async queue of its parent
+func (l *Log) With(key string, value interface{}) *Log {
+	child := *l
+	child.fields = make([]field, len(l.fields), len(l.fields)+1)
+	copy(child.fields, l.fields)
+
+	for i, f := range child.fields {
+		if f.key == key {
+			child.fields[i].value = value
+			return &child
+		}
+	}
+
+	child.fields = append(child.fields, field{key: key, value: value})
+
+	return &child
+}
+
+// does the actual writing to the log file
+func writeFile(filename string, line string) error {
+	switch filename {
+	case STDOUT:
+		_, err := fmt.Fprintln(os.Stdout, line)
+		return err
+	case STDERR:
+		_, err := fmt.Fprintln(os.Stderr, line)
+		return err
+	}
+
 	// Open the file for appending (or create it if it doesn't exist)
-	file, err := os.OpenFile(l.Filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		return err
 	}
@@ -117,19 +177,27 @@ func (l *Log) writeLog(line string) error {
 	return nil
 }
 
-func composeLogMessage(message string, messageType string) string {
-	file, line := getCallerInfo(3)
-	currentTime := time.Now()
-	return fmt.Sprintf(FORMAT, currentTime.Format(DATETIME_FORMAT), line, file, messageType, message)
+func (l *Log) composeLogMessage(message string, messageType string) string {
+	caller := getCallerInfo(3)
+	return l.render(entry{
+		time:    time.Now(),
+		level:   messageType,
+		message: message,
+		caller:  caller,
+	})
 }
 
-func getCallerInfo(level int) (string, int) {
+func getCallerInfo(level int) callerInfo {
 	// Get information about the caller at depth 1 (the immediate caller)
-	_, file, line, ok := runtime.Caller(level)
+	pc, file, line, ok := runtime.Caller(level)
 	if ok {
-		return file, line
+		function := "Unknown"
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			function = fn.Name()
+		}
+		return callerInfo{file: file, line: line, function: function}
 	} else {
 		// this will most likely never be reached
-		return "Unknown", 0
+		return callerInfo{file: "Unknown", line: 0, function: "Unknown"}
 	}
 }
@@ -212,3 +212,16 @@ func fileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return !os.IsNotExist(err)
 }
+
+func TestDisabledLevelsShouldNotAllocate(t *testing.T) {
+	l := New()
+	l.LogLevels = []string{ERROR}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Info("This is an info log")
+	})
+
+	if allocs != 0 {
+		t.Fatalf(`l.Info() of a disabled level allocations = %v, want 0`, allocs)
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/utils/httpUtil"
+)
+
+const (
+	DEFAULT_REQUEST_ID_HEADER = "X-Request-ID"
+	// longer incoming request IDs are replaced
+	MAX_REQUEST_ID_LENGTH = 128
+)
+
+type RequestLoggerOptions struct {
+	// header used to read an incoming request ID and to echo it back, defaults to X-Request-ID
+	RequestIDHeader string
+	// read the client IP from X-Forwarded-For / X-Real-IP, only enable behind a trusted proxy.
+	// Only the entry added by the proxy is used, see httpUtil.ClientIP()
+	TrustProxy bool
+	// resolves the authenticated user, evaluated when the logger is retrieved so that
+	// middlewares running after this one (e.g. authentication) are taken into account
+	UserID func(*http.Request) string
+}
+
+type contextKey int
+
+const (
+	requestLoggerKey contextKey = iota
+	accessLogKey
+)
+
+type requestLogger struct {
+	log       *Log
+	requestID string
+	userID    func(*http.Request) string
+}
+
+// public: middleware that attaches a child of base to every request, tagged with the request metadata.
+// Register it with router.Instance().Use() and retrieve the logger in handlers with FromRequest()
+func RequestLogger(base *Log, options RequestLoggerOptions) func(http.Handler) http.Handler {
+	if options.RequestIDHeader == "" {
+		options.RequestIDHeader = DEFAULT_REQUEST_ID_HEADER
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the id ends up in log lines and in the response, only take over the ones that look like ids
+			requestID := r.Header.Get(options.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(options.RequestIDHeader, requestID)
+
+			child := base.
+				With("requestId", requestID).
+				With("method", r.Method).
+				With("path", r.URL.Path)
+
+			if rt := router.CurrentRoute(r); rt != nil {
+				name := rt.Name()
+				if name == "" {
+					name = rt.Path()
+				}
+				child = child.With("route", name)
+			}
+
+			child = child.With("remoteIp", httpUtil.ClientIP(r, options.TrustProxy))
+
+			ctx := context.WithValue(r.Context(), requestLoggerKey, &requestLogger{
+				log:       child,
+				requestID: requestID,
+				userID:    options.UserID,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// public: returns the request scoped logger, falls back to a logger writing to STDERR
+// when the request did not go through RequestLogger()
+func FromRequest(r *http.Request) *Log {
+	rl, ok := r.Context().Value(requestLoggerKey).(*requestLogger)
+	if !ok {
+		l := New()
+		l.Filename = STDERR
+		return l
+	}
+
+	if rl.userID != nil {
+		if userID := rl.userID(r); userID != "" {
+			return rl.log.With("userId", userID)
+		}
+	}
+
+	return rl.log
+}
+
+// public: returns the ID assigned to the request by RequestLogger(), empty when there is none
+func RequestID(r *http.Request) string {
+	rl, ok := r.Context().Value(requestLoggerKey).(*requestLogger)
+	if !ok {
+		return ""
+	}
+
+	return rl.requestID
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// 1 to MAX_REQUEST_ID_LENGTH characters out of A-Z, a-z, 0-9, '.', '_' and '-'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
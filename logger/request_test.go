@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRequestLoggerShouldTagLines(t *testing.T) {
+	l := New()
+	l.Filename = "test_request.log"
+	l.Template = "{message} {fields}"
+
+	defer os.Remove(l.Filename)
+
+	middleware := RequestLogger(l, RequestLoggerOptions{
+		UserID: func(r *http.Request) string { return r.Header.Get("X-User") },
+	})
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// simulates an authentication middleware running after the request logger
+		r.Header.Set("X-User", "42")
+		FromRequest(r).Info("This is an info log")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/product/1", nil)
+	req.Header.Set(DEFAULT_REQUEST_ID_HEADER, "abc")
+	req.RemoteAddr = "10.0.0.1:1234"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	lastLine, err := ReadLastLine(l.Filename)
+	want := "This is an info log requestId=abc method=GET path=/api/product/1 remoteIp=10.0.0.1 userId=42"
+
+	if lastLine != want || err != nil {
+		t.Fatalf(`FromRequest(r).Info() = %q, %v, want %q, nil`, lastLine, err, want)
+	}
+
+	if rec.Header().Get(DEFAULT_REQUEST_ID_HEADER) != "abc" {
+		t.Fatalf(`response %s = %q, want "abc"`, DEFAULT_REQUEST_ID_HEADER, rec.Header().Get(DEFAULT_REQUEST_ID_HEADER))
+	}
+}
+
+func TestRequestLoggerShouldGenerateRequestID(t *testing.T) {
+	var requestID string
+
+	handler := RequestLogger(New(), RequestLoggerOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = RequestID(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(requestID) != 32 {
+		t.Fatalf(`RequestID(r) = %q, want a 32 character hex id`, requestID)
+	}
+}
+
+func TestRequestLoggerShouldReplaceInvalidRequestIDs(t *testing.T) {
+	ids := []string{strings.Repeat("a", MAX_REQUEST_ID_LENGTH+1), "abc\r\nX-Injected: 1", "a b", `"quoted"`}
+
+	for _, id := range ids {
+		var requestID string
+		handler := RequestLogger(New(), RequestLoggerOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = RequestID(r)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DEFAULT_REQUEST_ID_HEADER, id)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if requestID == id || len(requestID) != 32 || rec.Header().Get(DEFAULT_REQUEST_ID_HEADER) != requestID {
+			t.Fatalf(`RequestID(r) of %q = %q, want a new 32 character hex id`, id, requestID)
+		}
+	}
+}
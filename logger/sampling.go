@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SamplingOptions struct {
+	// log the first N occurrences of a message in each interval
+	First int
+	// after that, only log every Mth occurrence (0 drops the rest of the interval)
+	Thereafter int
+	Interval   time.Duration
+}
+
+// holds the state shared by a logger and its children to decide which lines get through
+type filter struct {
+	// set once sampling, a rate limit or deduplication is configured, lines skip the mutex before that
+	active atomic.Bool
+	mutex  sync.Mutex
+	now    func() time.Time
+
+	sampling     *SamplingOptions
+	samplingTick time.Time
+	counts       map[string]int
+
+	limits map[string]*bucket
+
+	dedupeWindow time.Duration
+	last         *duplicate
+
+	suppressed uint64
+}
+
+// token bucket used for the per level rate limit
+type bucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// the message currently being deduplicated
+type duplicate struct {
+	key     string
+	level   string
+	message string
+	caller  callerInfo
+	fields  []field
+	since   time.Time
+	repeats int
+}
+
+// public: log the first N occurrences of every distinct message per interval, then every Mth.
+// Messages are told apart by their level and text, the fields of request loggers are not taken into account
+func (l *Log) Sample(options SamplingOptions) *Log {
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+
+	f := l.getFilter()
+	f.mutex.Lock()
+	f.sampling = &options
+	f.counts = make(map[string]int)
+	f.mutex.Unlock()
+	f.active.Store(true)
+
+	return l
+}
+
+// public: allow at most perSecond lines of the given level, short bursts up to the same amount are accepted
+func (l *Log) RateLimit(level string, perSecond int) *Log {
+	f := l.getFilter()
+	f.mutex.Lock()
+	f.limits[level] = &bucket{rate: float64(perSecond), tokens: float64(perSecond), last: f.now()}
+	f.mutex.Unlock()
+	f.active.Store(true)
+
+	return l
+}
+
+// public: collapse identical consecutive lines logged within the window into a "repeated N times" summary,
+// written when a different line comes along or when the window is over
+func (l *Log) Dedupe(window time.Duration) *Log {
+	f := l.getFilter()
+	f.mutex.Lock()
+	f.dedupeWindow = window
+	f.mutex.Unlock()
+	f.active.Store(true)
+
+	return l
+}
+
+// public: number of lines dropped by sampling, rate limiting and deduplication
+func (l *Log) Suppressed() uint64 {
+	if l.filter == nil {
+		return 0
+	}
+
+	l.filter.mutex.Lock()
+	defer l.filter.mutex.Unlock()
+
+	return l.filter.suppressed
+}
+
+func newFilter() *filter {
+	return &filter{
+		now:    time.Now,
+		limits: make(map[string]*bucket),
+	}
+}
+
+// loggers that were not created with New() get their filter on first use, configure them before logging
+func (l *Log) getFilter() *filter {
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+
+	if l.filter == nil {
+		l.filter = newFilter()
+	}
+
+	return l.filter
+}
+
+var filterMutex sync.Mutex
+
+// decides whether a line of the given level should be written
+func (l *Log) allow(level string, message string) bool {
+	f := l.filter
+	if f == nil || !f.active.Load() {
+		return true
+	}
+
+	f.mutex.Lock()
+	now := f.now()
+	key := level + "|" + message
+	var summary *duplicate
+
+	allowed := f.dedupe(key, now, &summary) && f.sample(key, now) && f.limit(level, now)
+	if !allowed {
+		f.suppressed++
+	}
+
+	// the first repeat starts the run, its summary is written when the window is over
+	if f.last != nil && f.last.key == key && f.last.repeats == 1 {
+		run := f.last
+		time.AfterFunc(f.dedupeWindow-now.Sub(run.since), func() {
+			l.flushRun(run)
+		})
+	}
+
+	if allowed && f.dedupeWindow > 0 {
+		f.last = &duplicate{
+			key:     key,
+			level:   level,
+			message: message,
+			caller:  getCallerInfo(3),
+			fields:  l.fields,
+			since:   now,
+		}
+	}
+	f.mutex.Unlock()
+
+	if summary != nil {
+		l.writeSummary(summary)
+	}
+
+	return allowed
+}
+
+func (f *filter) dedupe(key string, now time.Time, summary **duplicate) bool {
+	if f.dedupeWindow <= 0 || f.last == nil {
+		return true
+	}
+
+	if f.last.key == key && now.Sub(f.last.since) < f.dedupeWindow {
+		f.last.repeats++
+		return false
+	}
+
+	// a different message (or the window is over) ends the current run
+	if f.last.repeats > 0 {
+		*summary = f.last
+	}
+	f.last = nil
+
+	return true
+}
+
+func (f *filter) sample(key string, now time.Time) bool {
+	if f.sampling == nil {
+		return true
+	}
+
+	// start counting from zero again every interval
+	if now.Sub(f.samplingTick) >= f.sampling.Interval {
+		f.samplingTick = now
+		f.counts = make(map[string]int)
+	}
+
+	f.counts[key]++
+	count := f.counts[key]
+
+	if count <= f.sampling.First {
+		return true
+	}
+
+	return f.sampling.Thereafter > 0 && (count-f.sampling.First)%f.sampling.Thereafter == 0
+}
+
+func (f *filter) limit(level string, now time.Time) bool {
+	b, ok := f.limits[level]
+	if !ok {
+		return true
+	}
+
+	// refill the bucket for the time that passed since the last line
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// writes the summary of the pending run of duplicates, if any
+func (l *Log) flushDuplicates() {
+	f := l.filter
+	if f == nil {
+		return
+	}
+
+	f.mutex.Lock()
+	last := f.last
+	f.last = nil
+	f.mutex.Unlock()
+
+	if last != nil && last.repeats > 0 {
+		l.writeSummary(last)
+	}
+}
+
+// writes the summary of the run unless it was already ended by another line
+func (l *Log) flushRun(run *duplicate) {
+	f := l.filter
+
+	f.mutex.Lock()
+	pending := f.last == run
+	if pending {
+		f.last = nil
+	}
+	f.mutex.Unlock()
+
+	if pending {
+		l.writeSummary(run)
+	}
+}
+
+func (l *Log) writeSummary(d *duplicate) {
+	origin := *l
+	origin.fields = d.fields
+
+	l.writeLog(origin.render(entry{
+		time:    time.Now(),
+		level:   d.level,
+		message: fmt.Sprintf("%s (repeated %s)", d.message, times(d.repeats)),
+		caller:  d.caller,
+	}))
+}
+
+func times(n int) string {
+	if n == 1 {
+		return "once"
+	}
+
+	return fmt.Sprintf("%d times", n)
+}
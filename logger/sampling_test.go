@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns a clock that only moves when told to
+func fakeClock(l *Log) *time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.getFilter().now = func() time.Time { return now }
+	return &now
+}
+
+func TestSampleShouldKeepFirstThenEveryNth(t *testing.T) {
+	l := New()
+	l.Filename = "test_sampling.log"
+
+	defer os.Remove(l.Filename)
+
+	now := fakeClock(l)
+	l.Sample(SamplingOptions{First: 2, Thereafter: 3, Interval: time.Second})
+
+	for i := 0; i < 8; i++ {
+		l.Error("This is an error log")
+	}
+
+	// 1, 2 then 5 and 8
+	if lines, _ := countLines(l.Filename); lines != 4 {
+		t.Fatalf(`sampled lines = %d, want 4`, lines)
+	}
+
+	// a new interval starts counting from zero again
+	*now = now.Add(time.Second)
+	l.Error("This is an error log")
+
+	if lines, _ := countLines(l.Filename); lines != 5 {
+		t.Fatalf(`sampled lines after interval = %d, want 5`, lines)
+	}
+
+	if l.Suppressed() != 4 {
+		t.Fatalf(`l.Suppressed() = %d, want 4`, l.Suppressed())
+	}
+}
+
+func TestRateLimitShouldOnlyAffectItsLevel(t *testing.T) {
+	l := New()
+	l.Filename = "test_sampling.log"
+
+	defer os.Remove(l.Filename)
+
+	now := fakeClock(l)
+	l.RateLimit(ERROR, 2)
+
+	for i := 0; i < 5; i++ {
+		l.Error("This is an error log")
+		l.Info("This is an info log")
+	}
+
+	if lines, _ := countLines(l.Filename); lines != 7 {
+		t.Fatalf(`rate limited lines = %d, want 7`, lines)
+	}
+
+	// half a second refills one token
+	*now = now.Add(500 * time.Millisecond)
+	l.Error("This is an error log")
+	l.Error("This is an error log")
+
+	if lines, _ := countLines(l.Filename); lines != 8 {
+		t.Fatalf(`rate limited lines after refill = %d, want 8`, lines)
+	}
+}
+
+func TestDedupeShouldWriteSummary(t *testing.T) {
+	l := New()
+	l.Filename = "test_sampling.log"
+	l.Template = "{level}: {message}"
+
+	defer os.Remove(l.Filename)
+
+	fakeClock(l)
+	l.Dedupe(time.Minute)
+
+	for i := 0; i < 4; i++ {
+		l.Error("This is an error log")
+	}
+	l.Info("This is an info log")
+	l.Info("This is an info log")
+	l.Close()
+
+	content, _ := os.ReadFile(l.Filename)
+	want := strings.Join([]string{
+		"ERROR: This is an error log",
+		"ERROR: This is an error log (repeated 3 times)",
+		"INFO: This is an info log",
+		"INFO: This is an info log (repeated once)",
+		"",
+	}, "\n")
+
+	if string(content) != want {
+		t.Fatalf(`deduplicated log = %q, want %q`, content, want)
+	}
+}
+
+func TestSamplingShouldIgnoreFieldsAndCoverChildren(t *testing.T) {
+	l := New()
+	l.Filename = "test_sampling.log"
+
+	defer os.Remove(l.Filename)
+
+	fakeClock(l)
+	// children made before Sample() share the filter of their parent
+	children := []*Log{l.With("requestId", "a"), l.With("requestId", "b"), l.With("requestId", "c")}
+	l.Sample(SamplingOptions{First: 1, Interval: time.Second})
+
+	for _, child := range children {
+		child.Error("This is an error log")
+	}
+
+	if lines, _ := countLines(l.Filename); lines != 1 {
+		t.Fatalf(`sampled lines of request loggers = %d, want 1`, lines)
+	}
+}
+
+func TestDedupeShouldWriteSummaryWhenTheWindowIsOver(t *testing.T) {
+	l := New()
+	l.Filename = "test_sampling.log"
+	l.Template = "{level}: {message}"
+
+	defer os.Remove(l.Filename)
+
+	l.Dedupe(20 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		l.Error("This is an error log")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	lastLine, _ := ReadLastLine(l.Filename)
+	want := "ERROR: This is an error log (repeated 2 times)"
+
+	if lastLine != want {
+		t.Fatalf(`last line after the window = %q, want %q`, lastLine, want)
+	}
+}
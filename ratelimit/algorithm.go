@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// counts a request against the state of the key, the state is changed in place
+func (l *Limiter) take(state *State, now time.Time) Result {
+	switch l.options.Algorithm {
+	case TOKEN_BUCKET:
+		return l.tokenBucket(state, now)
+	case FIXED_WINDOW:
+		return l.fixedWindow(state, now)
+	}
+
+	return l.slidingWindow(state, now)
+}
+
+// tokens per nanosecond
+func (l *Limiter) rate() float64 {
+	return float64(l.options.Limit) / float64(l.options.Window)
+}
+
+// State.Count holds the tokens left and State.Time when they were counted
+func (l *Limiter) tokenBucket(state *State, now time.Time) Result {
+	burst := float64(l.options.Burst)
+	rate := l.rate()
+
+	tokens := burst
+	if !state.Time.IsZero() {
+		tokens = math.Min(burst, state.Count+float64(now.Sub(state.Time))*rate)
+	}
+
+	result := Result{Limit: l.options.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate)
+	}
+
+	state.Count, state.Time = tokens, now
+
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((burst - tokens) / rate)
+
+	return result
+}
+
+// State.Count holds the requests of the window starting at State.Time
+func (l *Limiter) fixedWindow(state *State, now time.Time) Result {
+	window := l.options.Window
+	start := now.Truncate(window)
+
+	if !state.Time.Equal(start) {
+		state.Count, state.Time = 0, start
+	}
+
+	result := Result{Limit: l.options.Limit, Reset: start.Add(window).Sub(now)}
+	if state.Count < float64(l.options.Limit) {
+		state.Count++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = result.Reset
+	}
+
+	result.Remaining = l.options.Limit - int(state.Count)
+
+	return result
+}
+
+// State.Count holds the requests of the window starting at State.Time and State.Previous the ones
+// of the window before. The requests of the last window are estimated as if the previous window's
+// were spread evenly over it
+func (l *Limiter) slidingWindow(state *State, now time.Time) Result {
+	window := l.options.Window
+	limit := float64(l.options.Limit)
+	start := now.Truncate(window)
+
+	switch {
+	case state.Time.Equal(start):
+	case state.Time.Equal(start.Add(-window)):
+		state.Previous, state.Count, state.Time = state.Count, 0, start
+	default:
+		state.Previous, state.Count, state.Time = 0, 0, start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := state.Previous*weight + state.Count
+
+	result := Result{Limit: l.options.Limit, Reset: window - elapsed}
+	if estimate+1 <= limit {
+		state.Count++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.slidingRetry(state, elapsed)
+	}
+
+	result.Remaining = int(math.Max(0, limit-estimate))
+
+	return result
+}
+
+// how long until the estimate leaves room for one more request
+func (l *Limiter) slidingRetry(state *State, elapsed time.Duration) time.Duration {
+	window := float64(l.options.Window)
+	limit := float64(l.options.Limit)
+
+	// the requests of the current window alone are too many, wait for the next window and for
+	// enough of them to have slid out
+	if state.Count+1 > limit {
+		wait := window - float64(elapsed)
+		return time.Duration(wait + math.Max(0, window*(1-(limit-1)/state.Count)))
+	}
+
+	return time.Duration(math.Max(0, window*(1-(limit-state.Count-1)/state.Previous)-float64(elapsed)))
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// a limiter whose clock is at start plus the given offset
+type clock struct {
+	limiter *Limiter
+	start   time.Time
+}
+
+func newClock(options Options) *clock {
+	return &clock{limiter: NewLimiter(options), start: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) allow(t *testing.T, at time.Duration) Result {
+	c.limiter.now = func() time.Time { return c.start.Add(at) }
+
+	result, err := c.limiter.Allow("client")
+	if err != nil {
+		t.Fatalf(`Allow() error = %v, want nil`, err)
+	}
+
+	return result
+}
+
+func near(a time.Duration, b time.Duration) bool {
+	return (a - b).Abs() < time.Millisecond
+}
+
+func TestFixedWindowShouldResetAtTheEndOfTheWindow(t *testing.T) {
+	c := newClock(Options{Algorithm: FIXED_WINDOW, Limit: 3, Window: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		if result := c.allow(t, 10*time.Second); !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf(`request %d = %+v, want allowed with %d remaining`, i, result, 2-i)
+		}
+	}
+
+	result := c.allow(t, 10*time.Second)
+	if result.Allowed || result.Remaining != 0 || !near(result.RetryAfter, 50*time.Second) {
+		t.Fatalf(`request over the limit = %+v, want refused for 50s`, result)
+	}
+
+	if result := c.allow(t, time.Minute); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf(`request of the next window = %+v, want allowed with 2 remaining`, result)
+	}
+}
+
+func TestSlidingWindowShouldWeighThePreviousWindow(t *testing.T) {
+	c := newClock(Options{Algorithm: SLIDING_WINDOW, Limit: 10, Window: time.Minute})
+
+	for i := 0; i < 10; i++ {
+		if result := c.allow(t, 30*time.Second); !result.Allowed {
+			t.Fatalf(`request %d = %+v, want allowed`, i, result)
+		}
+	}
+
+	// the 10 requests still count for 90% of them 6s into the next window
+	result := c.allow(t, 30*time.Second)
+	if result.Allowed || !near(result.RetryAfter, 36*time.Second) {
+		t.Fatalf(`request over the limit = %+v, want refused for 36s`, result)
+	}
+
+	if result := c.allow(t, 65*time.Second); result.Allowed {
+		t.Fatalf(`request 5s into the next window = %+v, want refused`, result)
+	}
+
+	result = c.allow(t, 66*time.Second)
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf(`request 6s into the next window = %+v, want allowed with 0 remaining`, result)
+	}
+
+	// the previous window no longer counts two windows later
+	if result := c.allow(t, 3*time.Minute); !result.Allowed || result.Remaining != 9 {
+		t.Fatalf(`request two windows later = %+v, want allowed with 9 remaining`, result)
+	}
+}
+
+func TestTokenBucketShouldRefillSteadily(t *testing.T) {
+	// a token per second, up to 5 at once
+	c := newClock(Options{Algorithm: TOKEN_BUCKET, Limit: 10, Window: 10 * time.Second, Burst: 5})
+
+	for i := 0; i < 5; i++ {
+		if result := c.allow(t, 0); !result.Allowed || result.Remaining != 4-i || result.Limit != 5 {
+			t.Fatalf(`request %d = %+v, want allowed with %d remaining`, i, result, 4-i)
+		}
+	}
+
+	result := c.allow(t, 0)
+	if result.Allowed || !near(result.RetryAfter, time.Second) || !near(result.Reset, 5*time.Second) {
+		t.Fatalf(`request over the burst = %+v, want refused for 1s`, result)
+	}
+
+	for i := 0; i < 2; i++ {
+		if result := c.allow(t, 2500*time.Millisecond); !result.Allowed {
+			t.Fatalf(`request %d after 2.5s = %+v, want allowed`, i, result)
+		}
+	}
+
+	result = c.allow(t, 2500*time.Millisecond)
+	if result.Allowed || !near(result.RetryAfter, 500*time.Millisecond) {
+		t.Fatalf(`third request after 2.5s = %+v, want refused for 0.5s`, result)
+	}
+
+	// the bucket never holds more than the burst
+	for i := 0; i < 5; i++ {
+		c.allow(t, time.Hour)
+	}
+	if result := c.allow(t, time.Hour); result.Allowed {
+		t.Fatalf(`request over the burst after an hour = %+v, want refused`, result)
+	}
+}
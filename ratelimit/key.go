@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/utils/httpUtil"
+)
+
+// identifies the client of a request, empty when the request has nothing to identify it by
+type KeyFunc func(r *http.Request) string
+
+// Public: keys requests by the IP of the client. Only trust the X-Forwarded-For and X-Real-IP
+// headers behind a proxy setting them, the address the proxy got the request from is used
+func ByIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) string {
+		// requests made up by the app itself have no address
+		ip := httpUtil.ClientIP(r, trustProxy)
+		if ip == "" {
+			return ""
+		}
+
+		return "ip:" + ip
+	}
+}
+
+// Public: keys requests by the authenticated user, requires Authenticate() of the auth package to
+// run first. Combine it with FirstOf() to limit anonymous requests by IP
+func ByUser(r *http.Request) string {
+	if id := auth.UserID(r); id != "" {
+		return "user:" + id
+	}
+
+	return ""
+}
+
+// Public: keys requests by the API key sent in the header, or as "Authorization: Bearer <key>"
+// when the header is empty. Only a hash of the key ends up in the store
+func ByAPIKey(header string) KeyFunc {
+	return func(r *http.Request) string {
+		var key string
+		if header != "" {
+			key = r.Header.Get(header)
+		} else if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			key = strings.TrimSpace(token)
+		}
+
+		if key == "" {
+			return ""
+		}
+
+		hash := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(hash[:16])
+	}
+}
+
+// Public: keys requests by the route they matched, all clients share the limit of the route.
+// Combine it with Combine() to limit every client per route
+func ByRoute(r *http.Request) string {
+	rt := router.CurrentRoute(r)
+	if rt == nil {
+		return ""
+	}
+
+	name := rt.Name()
+	if name == "" {
+		name = rt.Path()
+	}
+
+	return "route:" + r.Method + " " + name
+}
+
+// Public: uses the first key found, e.g. FirstOf(ByUser, ByIP(false))
+func FirstOf(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+
+		return ""
+	}
+}
+
+// Public: joins the keys, e.g. Combine(ByRoute, ByIP(false)). Requests missing one of them are not limited
+func Combine(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			k := key(r)
+			if k == "" {
+				return ""
+			}
+			parts = append(parts, k)
+		}
+
+		return strings.Join(parts, "|")
+	}
+}
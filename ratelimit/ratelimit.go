@@ -0,0 +1,173 @@
+// Package ratelimit limits how many requests a client can make in a period of time, with a token
+// bucket, a sliding window or a fixed window. Clients are told about their quota with the
+// RateLimit-* headers and refused requests are answered with 429 and Retry-After.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+)
+
+// algorithm constants
+const (
+	TOKEN_BUCKET   = "TOKEN_BUCKET"   // tokens refill steadily up to the burst, every request takes one
+	SLIDING_WINDOW = "SLIDING_WINDOW" // the requests of the last window, weighing the previous window in
+	FIXED_WINDOW   = "FIXED_WINDOW"   // the requests since the start of the window, windows start at fixed times
+)
+
+const (
+	DEFAULT_LIMIT  = 60
+	DEFAULT_WINDOW = time.Minute
+)
+
+type Options struct {
+	// defaults to SLIDING_WINDOW
+	Algorithm string
+	// requests allowed per window, defaults to DEFAULT_LIMIT
+	Limit int
+	// defaults to DEFAULT_WINDOW
+	Window time.Duration
+	// the tokens a bucket holds, the requests a client can make at once after being idle.
+	// TOKEN_BUCKET only, defaults to Limit
+	Burst int
+	// identifies the client of the request, defaults to ByIP(false). Requests without a key are
+	// not limited
+	Key KeyFunc
+	// defaults to a new MemoryStore, use a shared store when running several instances of the app
+	Store Store
+	// prefix of the keys in the store, required to tell limiters apart when they share a store and
+	// have the same limit. Defaults to the algorithm, the limit and the window
+	Name string
+}
+
+// the outcome of a request against the limit
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// until the quota is whole again
+	Reset time.Duration
+	// until a request is allowed again, zero when allowed
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	options Options
+	now     func() time.Time
+}
+
+// Public: creates the middleware limiting the requests, attach it to a route with Use() or to a
+// group of routes
+func New(options Options) func(http.Handler) http.Handler {
+	return NewLimiter(options).Middleware
+}
+
+// Public: creates a limiter, use its Middleware or call Allow() for limits outside of requests
+func NewLimiter(options Options) *Limiter {
+	if options.Algorithm == "" {
+		options.Algorithm = SLIDING_WINDOW
+	}
+
+	if options.Limit <= 0 {
+		options.Limit = DEFAULT_LIMIT
+	}
+
+	if options.Window <= 0 {
+		options.Window = DEFAULT_WINDOW
+	}
+
+	if options.Burst <= 0 {
+		options.Burst = options.Limit
+	}
+
+	if options.Key == nil {
+		options.Key = ByIP(false)
+	}
+
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+
+	if options.Name == "" {
+		options.Name = fmt.Sprintf("%s:%d/%s", options.Algorithm, options.Limit, options.Window)
+	}
+
+	return &Limiter{options: options, now: time.Now}
+}
+
+// counts a request of the key against the limit
+func (l *Limiter) Allow(key string) (Result, error) {
+	var result Result
+
+	err := l.options.Store.Update(l.options.Name+"|"+key, l.ttl(), func(state *State) {
+		result = l.take(state, l.now())
+	})
+
+	return result, err
+}
+
+// limits the requests passing through it. When the store fails the request is let through,
+// a broken store should not take the app down
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.options.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := l.Allow(key)
+		if err != nil {
+			logger.FromRequest(r).Error("ratelimit: " + err.Error())
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Policy", l.policy())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", seconds(result.RetryAfter))
+			logger.FromRequest(r).Notice("ratelimit: refused " + r.Method + " " + r.URL.Path + " of " + key)
+			router.Error(w, r, http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// the RateLimit-Policy header, e.g. 100;w=60
+func (l *Limiter) policy() string {
+	policy := strconv.Itoa(l.options.Limit) + ";w=" + seconds(l.options.Window)
+	if l.options.Algorithm == TOKEN_BUCKET {
+		policy += ";burst=" + strconv.Itoa(l.options.Burst)
+	}
+
+	return policy
+}
+
+// how long the store has to keep the state of a key, after that the state is as good as new
+func (l *Limiter) ttl() time.Duration {
+	switch l.options.Algorithm {
+	case TOKEN_BUCKET:
+		return time.Duration(float64(l.options.Burst) / l.rate())
+	case SLIDING_WINDOW:
+		return 2 * l.options.Window
+	}
+
+	return l.options.Window
+}
+
+// whole seconds, rounded up so that clients do not retry too early
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
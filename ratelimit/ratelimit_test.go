@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/waponix/netgo/auth"
+	"github.com/waponix/netgo/router"
+)
+
+type brokenStore struct{}
+
+func (brokenStore) Update(string, time.Duration, func(*State)) error {
+	return errors.New("connection refused")
+}
+
+func serve(handler http.Handler, path string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestMiddlewareShouldLimitRoutesAndGroups(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := router.Instance()
+	r.Register(
+		router.Get("/ratelimit/route", ok).Use(New(Options{Limit: 2, Window: time.Minute})),
+		router.Get("/ratelimit/free", ok),
+	).RegisterGroups(
+		router.Group("/ratelimit/group",
+			router.Get("/a", ok),
+			router.Get("/b", ok),
+		).Use(New(Options{Algorithm: FIXED_WINDOW, Limit: 1, Window: time.Minute})),
+	)
+
+	for i := 0; i < 2; i++ {
+		rec := serve(r.Mux(), "/ratelimit/route", "192.0.2.1:1234")
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Policy") != "2;w=60" {
+			t.Fatalf(`request %d = %d %v, want 200 with the RateLimit headers`, i, rec.Code, rec.Header())
+		}
+	}
+
+	rec := serve(r.Mux(), "/ratelimit/route", "192.0.2.1:1234")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf(`request over the limit = %d %v, want 429 with Retry-After`, rec.Code, rec.Header())
+	}
+
+	if rec := serve(r.Mux(), "/ratelimit/route", "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Fatalf(`request of another client status = %d, want 200`, rec.Code)
+	}
+
+	if rec := serve(r.Mux(), "/ratelimit/free", "192.0.2.1:1234"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf(`request of a route without limit = %d %v, want 200 without RateLimit headers`, rec.Code, rec.Header())
+	}
+
+	// the routes of a group share its limit
+	if rec := serve(r.Mux(), "/ratelimit/group/a", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf(`first request of the group status = %d, want 200`, rec.Code)
+	}
+
+	if rec := serve(r.Mux(), "/ratelimit/group/b", "192.0.2.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf(`second request of the group status = %d, want 429`, rec.Code)
+	}
+}
+
+func TestMiddlewareShouldLetRequestsThroughWhenTheStoreFails(t *testing.T) {
+	handler := New(Options{Limit: 1, Store: brokenStore{}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 3; i++ {
+		if rec := serve(handler, "/", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf(`request %d with a broken store status = %d, want 200`, i, rec.Code)
+		}
+	}
+}
+
+func TestKeysShouldIdentifyClients(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("Authorization", "Bearer key-123")
+
+	cases := []struct {
+		name string
+		key  KeyFunc
+		want string
+	}{
+		{"ip", ByIP(false), "ip:192.0.2.1"},
+		// the client can make up the entries before the one added by the proxy
+		{"ip behind a proxy", ByIP(true), "ip:10.0.0.1"},
+		{"anonymous user", ByUser, ""},
+		{"user or ip", FirstOf(ByUser, ByIP(false)), "ip:192.0.2.1"},
+		{"missing api key", ByAPIKey("X-API-Key"), ""},
+		{"route outside of the router", Combine(ByRoute, ByIP(false)), ""},
+	}
+
+	for _, c := range cases {
+		if got := c.key(req); got != c.want {
+			t.Fatalf(`%s key = %q, want %q`, c.name, got, c.want)
+		}
+	}
+
+	if got := FirstOf(ByUser, ByIP(false))(auth.WithUser(req, &auth.User{ID: "7"})); got != "user:7" {
+		t.Fatalf(`user or ip key of a user = %q, want "user:7"`, got)
+	}
+
+	if got := ByIP(false)(&http.Request{}); got != "" {
+		t.Fatalf(`ip key of a request without address = %q, want ""`, got)
+	}
+
+	if got := ByAPIKey("")(req); got == "" || got == "key:key-123" {
+		t.Fatalf(`api key = %q, want a hash of the key`, got)
+	}
+}
+
+func TestMemoryStoreShouldForgetExpiredKeys(t *testing.T) {
+	store := NewMemoryStore()
+	store.Update("a", time.Hour, func(s *State) { s.Count = 3 })
+	store.Update("b", -time.Second, func(s *State) { s.Count = 3 })
+
+	store.Update("a", time.Hour, func(s *State) {
+		if s.Count != 3 {
+			t.Fatalf(`state of a = %v, want the saved one`, s)
+		}
+	})
+
+	store.Update("b", time.Hour, func(s *State) {
+		if s.Count != 0 {
+			t.Fatalf(`state of the expired b = %v, want a new one`, s)
+		}
+	})
+
+	store.Update("c", -time.Second, func(*State) {})
+	store.lastSweep = time.Time{}
+	store.Update("a", time.Hour, func(*State) {})
+
+	if store.Len() != 2 {
+		t.Fatalf(`Len() after a sweep = %d, want 2`, store.Len())
+	}
+}
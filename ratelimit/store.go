@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// how often the memory store forgets expired keys
+const SWEEP_INTERVAL = time.Minute
+
+// what an algorithm keeps per key
+type State struct {
+	// the tokens left, or the requests of the current window
+	Count float64
+	// the requests of the previous window, SLIDING_WINDOW only
+	Previous float64
+	// when the tokens were counted, or the start of the current window
+	Time time.Time
+}
+
+// keeps the state of the keys, implement it to share limits between instances of the app
+type Store interface {
+	// runs update on the state of the key and saves the result. The state is the zero State for
+	// keys that are unknown or were not updated for ttl. Updates of a key must not run concurrently,
+	// or requests could be let through twice
+	Update(key string, ttl time.Duration, update func(*State)) error
+}
+
+// keeps the state of the keys in memory, every instance of the app has its own limits
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// Public: creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Update(key string, ttl time.Duration, update func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= SWEEP_INTERVAL {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+
+	update(&entry.state)
+	entry.expires = now.Add(ttl)
+
+	return nil
+}
+
+// the number of keys kept
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastSweep = now
+}
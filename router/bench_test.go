@@ -0,0 +1,85 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// the routes used by the benchmarks, registered on both the tree and gorilla/mux
+var benchRoutes = []string{
+	"/",
+	"/product",
+	"/product/featured",
+	"/product/{productId}",
+	"/product/{productId}/reviews",
+	"/product/{productId}/reviews/{reviewId}",
+	"/category",
+	"/category/{category}",
+	"/category/{category}/product/{productId}",
+	"/user/{userId}",
+	"/user/{userId}/orders",
+	"/user/{userId}/orders/{orderId}",
+}
+
+func noop(w http.ResponseWriter, r *http.Request) {}
+
+func benchTree() http.Handler {
+	r := newRouter()
+	for _, path := range benchRoutes {
+		r.Register(Get(path, noop))
+	}
+
+	return r.Mux()
+}
+
+func benchGorilla() http.Handler {
+	r := mux.NewRouter()
+	for _, path := range benchRoutes {
+		r.HandleFunc(path, noop).Methods(GET)
+	}
+
+	return r
+}
+
+func benchmarkServe(b *testing.B, handler http.Handler, path string) {
+	req := httptest.NewRequest(GET, path, nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkTreeStatic(b *testing.B) {
+	benchmarkServe(b, benchTree(), "/product/featured")
+}
+
+func BenchmarkGorillaStatic(b *testing.B) {
+	benchmarkServe(b, benchGorilla(), "/product/featured")
+}
+
+func BenchmarkTreeParams(b *testing.B) {
+	benchmarkServe(b, benchTree(), "/user/42/orders/7")
+}
+
+func BenchmarkGorillaParams(b *testing.B) {
+	benchmarkServe(b, benchGorilla(), "/user/42/orders/7")
+}
+
+func BenchmarkTreeLookup(b *testing.B) {
+	m := benchTree().(*Mux)
+	req := httptest.NewRequest(GET, "/user/42/orders/7", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m.release(m.find(req, req.URL.Path))
+	}
+}
@@ -0,0 +1,64 @@
+package router
+
+// routes sharing a path prefix and middlewares, register it with RegisterGroups()
+type RouteGroup struct {
+	prefix      string
+	routes      []RouteInterface
+	middlewares []RouteMiddlewareFunc
+	declared    []Middleware
+}
+
+// Public: creates a group of routes under the prefix
+func Group(prefix string, rts ...RouteInterface) *RouteGroup {
+	return &RouteGroup{prefix: prefix, routes: rts}
+}
+
+// adds routes to the group
+func (g *RouteGroup) Add(rts ...RouteInterface) *RouteGroup {
+	g.routes = append(g.routes, rts...)
+	return g
+}
+
+// adds middlewares wrapping every route of the group, they run before the middlewares of the routes
+func (g *RouteGroup) Use(middlewares ...RouteMiddlewareFunc) *RouteGroup {
+	g.middlewares = append(g.middlewares, middlewares...)
+	return g
+}
+
+// adds middlewares wrapping every route of the group like Use(), they are also kept on the routes
+// like the ones added with the With() of a route
+func (g *RouteGroup) With(middlewares ...Middleware) *RouteGroup {
+	for _, m := range middlewares {
+		g.middlewares = append(g.middlewares, m.Wrap)
+	}
+	g.declared = append(g.declared, middlewares...)
+	return g
+}
+
+// returns copies of the routes with the prefix and the middlewares of the group applied
+func (g *RouteGroup) Routes() []RouteInterface {
+	rts := make([]RouteInterface, 0, len(g.routes))
+	for _, rt := range g.routes {
+		clone := rt.Clone().SetPath(joinPaths(g.prefix, rt.Path())).(*route)
+
+		for _, method := range clone.Methods() {
+			middlewares := append(append([]RouteMiddlewareFunc(nil), g.middlewares...), clone.Middlewares(method)...)
+			declared := append(append([]Middleware(nil), g.declared...), clone.Declared(method)...)
+			clone.SetHandler(method, clone.Handler(method), middlewares...)
+			clone.declare(method, declared)
+		}
+
+		rts = append(rts, clone)
+	}
+
+	return rts
+}
+
+// register the routes of groups
+func (_router *router) RegisterGroups(groups ...*RouteGroup) *router {
+	for _, g := range groups {
+		_router.register(g.Routes())
+	}
+
+	return _router
+}
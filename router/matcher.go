@@ -0,0 +1,243 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+// the conditions besides the path that a request has to meet to match a route
+type matchers struct {
+	host       *hostTemplate
+	schemes    []string
+	headers    [][2]string
+	queries    [][2]string
+	predicates []predicate
+}
+
+// a custom matcher, the name identifies it in the route key since functions can not be compared
+type predicate struct {
+	name string
+	fn   func(*http.Request) bool
+}
+
+// source of the names given to predicates added through MatcherFunc()
+var predicateCount atomic.Uint64
+
+// a host like {subdomain}.example.com compiled into a regular expression
+type hostTemplate struct {
+	template string
+	regexp   *regexp.Regexp
+	names    []string
+}
+
+var templateParam = regexp.MustCompile(`\{([^{}:]+)(?::([^{}]+))?\}`)
+
+func compileHost(template string) *hostTemplate {
+	var pattern strings.Builder
+	var names []string
+	last := 0
+
+	for _, loc := range templateParam.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+
+		names = append(names, template[loc[2]:loc[3]])
+		if loc[4] >= 0 {
+			pattern.WriteString("(" + template[loc[4]:loc[5]] + ")")
+		} else {
+			// a param without a pattern never spans more than one label
+			pattern.WriteString(`([^.]+)`)
+		}
+
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+
+	return &hostTemplate{
+		template: template,
+		regexp:   regexp.MustCompile("(?i)^" + pattern.String() + "$"),
+		names:    names,
+	}
+}
+
+// returns the params captured from the host, the port is ignored unless the template has one
+func (h *hostTemplate) match(host string) (map[string]string, bool) {
+	if !strings.Contains(h.template, ":") {
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+	}
+
+	values := h.regexp.FindStringSubmatch(host)
+	if values == nil {
+		return nil, false
+	}
+
+	params := make(map[string]string, len(h.names))
+	for i, name := range h.names {
+		params[name] = values[i+1]
+	}
+
+	return params, true
+}
+
+// only match requests to the host, params like {subdomain}.example.com are available through Params()
+func (_route *route) Host(template string) RouteInterface {
+	_route.matchers.host = compileHost(template)
+	return _route
+}
+
+// only match requests made with one of the schemes, e.g. "https"
+func (_route *route) Schemes(schemes ...string) RouteInterface {
+	for _, scheme := range schemes {
+		_route.matchers.schemes = append(_route.matchers.schemes, strings.ToLower(scheme))
+	}
+	return _route
+}
+
+// only match requests having the headers, given as key/value pairs. An empty value only requires
+// the header to be present
+func (_route *route) Headers(pairs ...string) RouteInterface {
+	_route.matchers.headers = append(_route.matchers.headers, toPairs(pairs)...)
+	return _route
+}
+
+// only match requests having the query parameters, given as key/value pairs. An empty value only
+// requires the parameter to be present
+func (_route *route) Queries(pairs ...string) RouteInterface {
+	_route.matchers.queries = append(_route.matchers.queries, toPairs(pairs)...)
+	return _route
+}
+
+// only match requests for which the predicate returns true
+func (_route *route) MatcherFunc(fn func(*http.Request) bool) RouteInterface {
+	// every function is unique, two routes never share a predicate added through here
+	return _route.condition("func#"+strconv.FormatUint(predicateCount.Add(1), 10), fn)
+}
+
+// adds a predicate under a name, routes at the same path with the same named predicates are merged
+func (_route *route) condition(name string, fn func(*http.Request) bool) *route {
+	_route.matchers.predicates = append(_route.matchers.predicates, predicate{name: name, fn: fn})
+	return _route
+}
+
+// reports whether the request meets the conditions of the route besides the path,
+// the params captured from the host are returned along
+func (_route *route) Match(r *http.Request) (map[string]string, bool) {
+	m := _route.matchers
+
+	var params map[string]string
+	if m.host != nil {
+		var ok bool
+		if params, ok = m.host.match(r.Host); !ok {
+			return nil, false
+		}
+	}
+
+	if len(m.schemes) > 0 && !sliceUtil.Contains(m.schemes, requestScheme(r)) {
+		return nil, false
+	}
+
+	for _, header := range m.headers {
+		values, ok := r.Header[http.CanonicalHeaderKey(header[0])]
+		if !ok || (header[1] != "" && !sliceUtil.Contains(values, header[1])) {
+			return nil, false
+		}
+	}
+
+	if len(m.queries) > 0 {
+		query := r.URL.Query()
+		for _, q := range m.queries {
+			if !query.Has(q[0]) || (q[1] != "" && !sliceUtil.Contains(query[q[0]], q[1])) {
+				return nil, false
+			}
+		}
+	}
+
+	for _, p := range m.predicates {
+		if !p.fn(r) {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// identifies the route by its path and conditions, routes with the same key are merged on register
+func (_route *route) Key() string {
+	conditions := _route.conditions()
+	if len(conditions) <= 0 {
+		return _route.path
+	}
+
+	return _route.path + " [" + strings.Join(conditions, " ") + "]"
+}
+
+func (_route *route) conditions() []string {
+	m := _route.matchers
+	var conditions []string
+
+	if m.host != nil {
+		conditions = append(conditions, "host="+m.host.template)
+	}
+
+	if len(m.schemes) > 0 {
+		schemes := append([]string(nil), m.schemes...)
+		sort.Strings(schemes)
+		conditions = append(conditions, "schemes="+strings.Join(schemes, ","))
+	}
+
+	for _, header := range sortedPairs(m.headers) {
+		conditions = append(conditions, "header:"+http.CanonicalHeaderKey(header[0])+"="+header[1])
+	}
+
+	for _, q := range sortedPairs(m.queries) {
+		conditions = append(conditions, "query:"+q[0]+"="+q[1])
+	}
+
+	for _, p := range m.predicates {
+		conditions = append(conditions, p.name)
+	}
+
+	return conditions
+}
+
+func requestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+func toPairs(values []string) [][2]string {
+	pairs := make([][2]string, 0, (len(values)+1)/2)
+	for i := 0; i < len(values); i += 2 {
+		pair := [2]string{values[i], ""}
+		if i+1 < len(values) {
+			pair[1] = values[i+1]
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs
+}
+
+func sortedPairs(pairs [][2]string) [][2]string {
+	sorted := append([][2]string(nil), pairs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i][0]+"="+sorted[i][1] < sorted[j][0]+"="+sorted[j][1]
+	})
+
+	return sorted
+}
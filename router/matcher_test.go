@@ -0,0 +1,120 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostShouldCaptureParams(t *testing.T) {
+	var params map[string]string
+	r := newRouter().Register(
+		Get("/product/{productId}", func(w http.ResponseWriter, r *http.Request) {
+			params = Params(r)
+		}).Host("{tenant}.example.com"),
+		Get("/product/{productId}", respond("default")),
+	)
+
+	serve(r, GET, "http://acme.example.com:8080/product/1")
+
+	if params["tenant"] != "acme" || params["productId"] != "1" {
+		t.Fatalf(`Params(r) = %v, want tenant "acme" and productId "1"`, params)
+	}
+
+	if body := serve(r, GET, "http://example.org/product/1").Body.String(); body != "default" {
+		t.Fatalf(`GET example.org/product/1 = %q, want "default"`, body)
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatalf(`r.Validate() = %v, want nil`, err)
+	}
+}
+
+func TestHeaderQuerySchemeAndPredicateMatchers(t *testing.T) {
+	r := newRouter().Register(
+		Get("/product", respond("v2")).Headers("X-API-Version", "2"),
+		Get("/product", respond("preview")).Queries("preview", ""),
+		Get("/product", respond("secure")).Schemes("https"),
+		Get("/product", respond("beta")).MatcherFunc(func(r *http.Request) bool {
+			_, err := r.Cookie("beta")
+			return err == nil
+		}),
+		Get("/product", respond("v1")),
+	)
+
+	v2 := httptest.NewRequest(GET, "/product", nil)
+	v2.Header.Set("X-API-Version", "2")
+
+	beta := httptest.NewRequest(GET, "/product", nil)
+	beta.AddCookie(&http.Cookie{Name: "beta", Value: "1"})
+
+	cases := []struct {
+		request *http.Request
+		want    string
+	}{
+		{httptest.NewRequest(GET, "/product", nil), "v1"},
+		{httptest.NewRequest(GET, "/product?preview", nil), "preview"},
+		{httptest.NewRequest(GET, "https://example.com/product", nil), "secure"},
+		{v2, "v2"},
+		{beta, "beta"},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		r.Mux().ServeHTTP(rec, c.request)
+
+		if rec.Body.String() != c.want {
+			t.Fatalf(`GET %s = %q, want %q`, c.request.URL, rec.Body.String(), c.want)
+		}
+	}
+}
+
+func TestValidateShouldCompareMatchers(t *testing.T) {
+	r := newRouter().SetOrder(ORDER_REGISTRATION).Register(
+		Get("/product", respond("v1")),
+		Get("/product", respond("v2")).Headers("X-API-Version", "2"),
+	)
+
+	var conflict *RouteConflictError
+	if err := r.Validate(); !errors.As(err, &conflict) || conflict.Kind != CONFLICT_SHADOWED {
+		t.Fatalf(`r.Validate() = %v, want the header route to be shadowed`, err)
+	}
+}
+
+func TestPredicatesFromTheSameFunctionShouldNotBeMerged(t *testing.T) {
+	has := func(name string) func(*http.Request) bool {
+		return func(r *http.Request) bool {
+			return r.URL.Query().Has(name)
+		}
+	}
+
+	r := newRouter().Register(
+		Get("/product", respond("a")).MatcherFunc(has("a")),
+		Get("/product", respond("b")).MatcherFunc(has("b")),
+	)
+
+	if r.Routes.Len() != 2 {
+		t.Fatalf(`r.Routes.Len() = %d, want 2`, r.Routes.Len())
+	}
+
+	if body := serve(r, GET, "/product?b").Body.String(); body != "b" {
+		t.Fatalf(`GET /product?b = %q, want "b"`, body)
+	}
+}
+
+func TestPredicatesShouldRunOncePerRequest(t *testing.T) {
+	calls := 0
+	r := newRouter().Register(
+		Get("/product/{productId}", respond("product")).MatcherFunc(func(*http.Request) bool {
+			calls++
+			return true
+		}),
+	)
+
+	serve(r, GET, "/product/1")
+
+	if calls != 1 {
+		t.Fatalf(`predicate calls = %d, want 1`, calls)
+	}
+}
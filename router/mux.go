@@ -0,0 +1,201 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type ErrorHandlerFunc func(http.ResponseWriter, *http.Request, int)
+
+// the http.Handler built from the registered routes
+type Mux struct {
+	root            *node
+	registration    bool
+	errorHandler    ErrorHandlerFunc
+	trailingSlash   string
+	cleanPath       bool
+	caseInsensitive bool
+	searches        sync.Pool
+}
+
+type param struct {
+	key   string
+	value string
+}
+
+// the route that matched a request along with the params captured from the path and the host.
+// It is the context of the request itself, which saves allocating a context to carry it
+type routeMatch struct {
+	context.Context
+	route        RouteInterface
+	params       []param
+	hostParams   map[string]string
+	errorHandler ErrorHandlerFunc
+	// backs params for routes with few of them, they do not need an allocation of their own
+	inline [4]param
+}
+
+func (m *routeMatch) Value(key any) any {
+	if key == routeKey {
+		return m
+	}
+
+	return m.Context.Value(key)
+}
+
+func newMux(_router *router) *Mux {
+	m := &Mux{
+		root:            &node{},
+		registration:    _router.order == ORDER_REGISTRATION,
+		errorHandler:    _router.errorHandler,
+		trailingSlash:   _router.trailingSlash,
+		cleanPath:       _router.cleanPath,
+		caseInsensitive: _router.caseInsensitive,
+	}
+
+	if m.errorHandler == nil {
+		m.errorHandler = defaultErrorHandler
+	}
+
+	m.searches.New = func() any {
+		return &search{}
+	}
+
+	for i, rt := range _router.OrderedRoutes() {
+		handler := chain(rt.Apply(), _router.middlewares)
+		m.root.insert(rt.Path(), &leafEntry{index: i, route: rt, handler: handler}, m.caseInsensitive)
+	}
+
+	return m
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	if m.cleanPath {
+		if cleaned := cleanPath(path); cleaned != path {
+			redirect(w, r, cleaned)
+			return
+		}
+	}
+
+	s := m.find(r, path)
+	if s.best == nil && m.trailingSlash != TRAILING_SLASH_STRICT && path != "/" {
+		// give the path with the trailing slash toggled a try
+		alternative := strings.TrimSuffix(path, "/")
+		if alternative == path {
+			alternative += "/"
+		}
+
+		m.release(s)
+		s = m.find(r, alternative)
+
+		if s.best != nil && m.trailingSlash == TRAILING_SLASH_REDIRECT {
+			m.release(s)
+			redirect(w, r, alternative)
+			return
+		}
+	}
+
+	entry := s.best
+	if entry == nil {
+		m.release(s)
+		m.errorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	match := &routeMatch{
+		Context:      r.Context(),
+		route:        entry.route,
+		hostParams:   s.bestHost,
+		errorHandler: m.errorHandler,
+	}
+
+	if len(s.bestValues) <= len(match.inline) {
+		match.params = match.inline[:len(s.bestValues)]
+	} else {
+		match.params = make([]param, len(s.bestValues))
+	}
+
+	for i, value := range s.bestValues {
+		match.params[i] = param{key: entry.names[i], value: value}
+	}
+	m.release(s)
+
+	entry.handler.ServeHTTP(w, r.WithContext(match))
+}
+
+// walks the tree for the path, the search has to be released once its result is read
+func (m *Mux) find(r *http.Request, path string) *search {
+	s := m.searches.Get().(*search)
+	s.request = r
+	s.registration = m.registration
+	s.values = s.values[:0]
+	s.best = nil
+	s.raw = path
+
+	if m.caseInsensitive {
+		path = lowerASCII(path)
+	}
+
+	m.root.lookup(path, s)
+
+	return s
+}
+
+func (m *Mux) release(s *search) {
+	s.request = nil
+	s.raw = ""
+	s.best = nil
+	s.bestHost = nil
+	m.searches.Put(s)
+}
+
+// Public: returns the params of the matched route, taken from the path and the host
+func Params(r *http.Request) map[string]string {
+	params := map[string]string{}
+
+	if match, ok := r.Context().Value(routeKey).(*routeMatch); ok {
+		for name, value := range match.hostParams {
+			params[name] = value
+		}
+		for _, p := range match.params {
+			params[p.key] = p.value
+		}
+	}
+
+	return params
+}
+
+// Public: returns a single param of the matched route, empty when there is no such param
+func Param(r *http.Request, name string) string {
+	match, ok := r.Context().Value(routeKey).(*routeMatch)
+	if !ok {
+		return ""
+	}
+
+	for _, p := range match.params {
+		if p.key == name {
+			return p.value
+		}
+	}
+
+	return match.hostParams[name]
+}
+
+// Public: responds with the status through the error handler of the router that served the request
+func Error(w http.ResponseWriter, r *http.Request, status int) {
+	if match, ok := r.Context().Value(routeKey).(*routeMatch); ok {
+		match.errorHandler(w, r, status)
+		return
+	}
+
+	defaultErrorHandler(w, r, status)
+}
+
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request, status int) {
+	http.Error(w, fmt.Sprintf("<h1>%s</h1>", http.StatusText(status)), status)
+}
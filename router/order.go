@@ -0,0 +1,203 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// route ordering constants, decides which route wins when several patterns match a path
+const (
+	ORDER_SPECIFICITY  = "SPECIFICITY"  // static segments before constrained params before params
+	ORDER_REGISTRATION = "REGISTRATION" // first registered route wins
+)
+
+// conflict kind constants
+const (
+	CONFLICT_AMBIGUOUS = "AMBIGUOUS" // both routes match exactly the same paths
+	CONFLICT_SHADOWED  = "SHADOWED"  // the route can never match because an earlier route matches all of its paths
+)
+
+type RouteConflictError struct {
+	Kind  string
+	Route RouteInterface
+	By    RouteInterface
+}
+
+func (e *RouteConflictError) Error() string {
+	switch e.Kind {
+	case CONFLICT_AMBIGUOUS:
+		return fmt.Sprintf("router: route %q is ambiguous with route %q", e.Route.Key(), e.By.Key())
+	default:
+		return fmt.Sprintf("router: route %q is shadowed by route %q", e.Route.Key(), e.By.Key())
+	}
+}
+
+// segment kind constants, the order is also the specificity rank
+const (
+	segmentStatic = iota
+	segmentPattern
+	segmentParam
+	segmentCatchAll
+)
+
+type segment struct {
+	kind    int
+	value   string
+	pattern *regexp.Regexp
+}
+
+// set how routes are ordered when building the mux, defaults to ORDER_SPECIFICITY
+func (_router *router) SetOrder(order string) *router {
+	_router.order = order
+	_router.mux = nil
+	return _router
+}
+
+// returns the registered routes in the order they are matched
+func (_router *router) OrderedRoutes() []RouteInterface {
+	routes := _router.Routes.Values()
+
+	if _router.order == ORDER_REGISTRATION {
+		return routes
+	}
+
+	// stable so that routes of the same specificity keep their registration order
+	sort.SliceStable(routes, func(i, j int) bool {
+		order := compareSpecificity(parsePath(routes[i].Path()), parsePath(routes[j].Path()))
+		if order == 0 {
+			// on the same path, routes with matchers have to be tried before the ones without
+			return conditionsOf(routes[i]) != "" && conditionsOf(routes[j]) == ""
+		}
+		return order < 0
+	})
+
+	return routes
+}
+
+// reports every route that is ambiguous with or shadowed by another route, meant to be called once on startup
+func (_router *router) Validate() error {
+	routes := _router.OrderedRoutes()
+	segments := make([][]segment, len(routes))
+	for i, rt := range routes {
+		segments[i] = parsePath(rt.Path())
+	}
+
+	var errs []error
+	for i, rt := range routes {
+		for k, seg := range segments[i] {
+			if seg.kind == segmentCatchAll && k < len(segments[i])-1 {
+				errs = append(errs, fmt.Errorf("router: catch-all %q has to be the last segment of route %q", seg.value, rt.Path()))
+			}
+		}
+	}
+
+	for j := range routes {
+		for i := 0; i < j; i++ {
+			if !covers(segments[i], segments[j]) || !coversConditions(routes[i], routes[j]) {
+				continue
+			}
+
+			kind := CONFLICT_SHADOWED
+			if covers(segments[j], segments[i]) && coversConditions(routes[j], routes[i]) {
+				kind = CONFLICT_AMBIGUOUS
+			}
+
+			errs = append(errs, &RouteConflictError{Kind: kind, Route: routes[j], By: routes[i]})
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// splits a path like /product/{id:[0-9]+} into its segments
+func parsePath(path string) []segment {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	segments := make([]segment, len(parts))
+
+	for i, part := range parts {
+		kind, name, pattern := segmentKind(part)
+
+		switch {
+		case kind == tokenStatic:
+			segments[i] = segment{kind: segmentStatic, value: part}
+		case kind == tokenCatchAll:
+			segments[i] = segment{kind: segmentCatchAll, value: name}
+		case pattern == "":
+			segments[i] = segment{kind: segmentParam, value: name}
+		default:
+			segments[i] = segment{
+				kind:    segmentPattern,
+				value:   name,
+				pattern: regexp.MustCompile("^(?:" + pattern + ")$"),
+			}
+		}
+	}
+
+	return segments
+}
+
+// negative when a is more specific than b
+func compareSpecificity(a []segment, b []segment) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].kind != b[i].kind {
+			return a[i].kind - b[i].kind
+		}
+	}
+
+	return len(b) - len(a)
+}
+
+// reports whether every path matched by b is also matched by a
+func covers(a []segment, b []segment) bool {
+	for i := range a {
+		// a catch-all takes the rest of the path, even when it is empty
+		if a[i].kind == segmentCatchAll {
+			return len(b) > i
+		}
+
+		if i >= len(b) {
+			return false
+		}
+
+		switch a[i].kind {
+		case segmentStatic:
+			if b[i].kind != segmentStatic || a[i].value != b[i].value {
+				return false
+			}
+		case segmentPattern:
+			switch b[i].kind {
+			case segmentStatic:
+				if !a[i].pattern.MatchString(b[i].value) {
+					return false
+				}
+			case segmentPattern:
+				if a[i].pattern.String() != b[i].pattern.String() {
+					return false
+				}
+			default:
+				return false
+			}
+		case segmentParam:
+			if b[i].kind == segmentCatchAll {
+				return false
+			}
+		}
+	}
+
+	return len(a) == len(b)
+}
+
+// returns the matchers part of the route key, empty when the route matches on the path only
+func conditionsOf(rt RouteInterface) string {
+	return strings.TrimPrefix(rt.Key(), rt.Path())
+}
+
+// reports whether the matchers of a accept every request accepted by the matchers of b,
+// matchers are only compared as a whole since predicates can not be inspected
+func coversConditions(a RouteInterface, b RouteInterface) bool {
+	return conditionsOf(a) == "" || conditionsOf(a) == conditionsOf(b)
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// trailing slash policy constants, decides what happens when /product/1/ is requested
+// but only /product/1 is registered (or the other way around)
+const (
+	TRAILING_SLASH_STRICT   = "STRICT"   // the paths are different, answer with 404
+	TRAILING_SLASH_REDIRECT = "REDIRECT" // redirect to the registered path
+	TRAILING_SLASH_IGNORE   = "IGNORE"   // serve the registered route as is
+)
+
+// set the trailing slash policy, defaults to TRAILING_SLASH_STRICT
+func (_router *router) SetTrailingSlash(policy string) *router {
+	_router.trailingSlash = policy
+	_router.mux = nil
+	return _router
+}
+
+// turn the redirect of unclean paths (duplicate slashes, "." and "..") to their clean form on or off,
+// it is on by default
+func (_router *router) SetCleanPath(clean bool) *router {
+	_router.cleanPath = clean
+	_router.mux = nil
+	return _router
+}
+
+// match the static parts of the routes regardless of their case, params keep the case of the request
+func (_router *router) SetCaseInsensitive(caseInsensitive bool) *router {
+	_router.caseInsensitive = caseInsensitive
+	_router.mux = nil
+	return _router
+}
+
+// resolves "." and "..", removes duplicate slashes and keeps the trailing slash
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	// most paths are clean already, spare them the allocations of path.Clean()
+	if isClean(p) {
+		return p
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// reports whether the path starts with a slash and has no empty, "." or ".." segments,
+// a trailing slash is fine
+func isClean(p string) bool {
+	if p[0] != '/' {
+		return false
+	}
+
+	for start := 1; start < len(p); {
+		end := strings.IndexByte(p[start:], '/')
+		if end < 0 {
+			end = len(p) - start
+		}
+
+		segment := p[start : start+end]
+		if segment == "." || segment == ".." || (segment == "" && start+end < len(p)) {
+			return false
+		}
+
+		start += end + 1
+	}
+
+	return true
+}
+
+// redirects to the path keeping the query, with 301 for GET and HEAD and 308 for the
+// other methods so that the body is sent again
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	// never hand out a protocol relative url pointing to another host
+	if strings.HasPrefix(target, "//") {
+		target = "/" + strings.TrimLeft(target, "/")
+	}
+
+	u := *r.URL
+	u.Scheme = ""
+	u.Host = ""
+	u.Path = target
+	u.RawPath = ""
+
+	status := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+
+	http.Redirect(w, r, u.String(), status)
+}
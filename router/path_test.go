@@ -0,0 +1,118 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRegisterGroupShouldJoinPaths(t *testing.T) {
+	cases := []struct {
+		group string
+		path  string
+		want  string
+	}{
+		{"/api", "/product/{productId}", "/api/product/{productId}"},
+		{"/api/", "/product", "/api/product"},
+		{"api", "product/", "/api/product/"},
+		{"/api", "/", "/api/"},
+	}
+
+	for _, c := range cases {
+		rt := Get(c.path, respond("ok"))
+		newRouter().RegisterGroup(c.group, rt)
+
+		if rt.Path() != c.want {
+			t.Fatalf(`RegisterGroup(%q, Get(%q)) path = %q, want %q`, c.group, c.path, rt.Path(), c.want)
+		}
+	}
+}
+
+func TestTrailingSlashPolicies(t *testing.T) {
+	routes := func() *router {
+		return newRouter().Register(
+			Get("/product/{productId}", respond("product")),
+			Post("/category/", respond("category")),
+		)
+	}
+
+	cases := []struct {
+		policy   string
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{TRAILING_SLASH_STRICT, GET, "/product/1/", http.StatusNotFound, ""},
+		{TRAILING_SLASH_REDIRECT, GET, "/product/1/?q=1", http.StatusMovedPermanently, "/product/1?q=1"},
+		{TRAILING_SLASH_REDIRECT, POST, "/category", http.StatusPermanentRedirect, "/category/"},
+		{TRAILING_SLASH_IGNORE, GET, "/product/1/", http.StatusOK, ""},
+	}
+
+	for _, c := range cases {
+		rec := serve(routes().SetTrailingSlash(c.policy), c.method, c.path)
+
+		if rec.Code != c.status || rec.Header().Get("Location") != c.location {
+			t.Fatalf(`%s %s with %s = %d %q, want %d %q`, c.method, c.path, c.policy, rec.Code, rec.Header().Get("Location"), c.status, c.location)
+		}
+	}
+}
+
+func TestCleanPathShouldRedirect(t *testing.T) {
+	r := newRouter().Register(Get("/product/{productId}", respond("product")))
+
+	cases := []struct {
+		path     string
+		location string
+	}{
+		{"/api/../product/1", "/product/1"},
+		{"//product//1", "/product/1"},
+		{"/product/./1/", "/product/1/"},
+	}
+
+	for _, c := range cases {
+		rec := serve(r, GET, c.path)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != c.location {
+			t.Fatalf(`GET %s = %d %q, want 301 %q`, c.path, rec.Code, rec.Header().Get("Location"), c.location)
+		}
+	}
+
+	if rec := serve(r.SetCleanPath(false), GET, "//product//1"); rec.Code != http.StatusNotFound {
+		t.Fatalf(`GET //product//1 without cleaning = %d, want 404`, rec.Code)
+	}
+}
+
+func TestCaseInsensitiveMatching(t *testing.T) {
+	r := newRouter().Register(Get("/Product/{productId}", echoParams))
+
+	if rec := serve(r, GET, "/product/AbC"); rec.Code != http.StatusNotFound {
+		t.Fatalf(`GET /product/AbC = %d, want 404 while matching is case sensitive`, rec.Code)
+	}
+
+	r.SetCaseInsensitive(true)
+	if body := serve(r, GET, "/PRODUCT/AbC").Body.String(); body != "/Product/{productId} productId=AbC" {
+		t.Fatalf(`GET /PRODUCT/AbC = %q, want the param to keep its case`, body)
+	}
+}
+
+func TestCleanPathShouldKeepCleanPaths(t *testing.T) {
+	cases := map[string]string{
+		"/":               "/",
+		"/product/1":      "/product/1",
+		"/product/1/":     "/product/1/",
+		"/product//1":     "/product/1",
+		"/product/./1":    "/product/1",
+		"/product/../1":   "/1",
+		"/product/1/.":    "/product/1",
+		"/product/1/..":   "/product",
+		"//product":       "/product",
+		"product":         "/product",
+		"/.well-known/x":  "/.well-known/x",
+		"/product/..1/.a": "/product/..1/.a",
+	}
+
+	for p, want := range cases {
+		if got := cleanPath(p); got != want {
+			t.Fatalf(`cleanPath(%q) = %q, want %q`, p, got, want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package router
+
+// the race detector makes values escape, allocation counts are meaningless with it
+const raceEnabled = true
@@ -3,9 +3,9 @@ package router
 import (
 	"net/http"
 	"strings"
+	"sync"
 
-	"github.com/gorilla/mux"
-	"github.com/waponix/netgo/utils/sliceUtil"
+	"github.com/waponix/netgo/utils/collections"
 )
 
 const (
@@ -16,6 +16,8 @@ const (
 	HEAD    = http.MethodHead
 	OPTIONS = http.MethodOptions
 	DELETE  = http.MethodDelete
+	// matches any request method that has no handler of its own
+	ANY = "*"
 )
 
 // ===== STARTOF Router =====
@@ -25,21 +27,42 @@ type RouterInterface interface {
 }
 
 type router struct {
-	Routes RoutesMap
+	Routes          *RoutesMap
+	middlewares     []RouteMiddlewareFunc
+	order           string
+	errorHandler    ErrorHandlerFunc
+	trailingSlash   string
+	cleanPath       bool
+	caseInsensitive bool
+	mux             *Mux
+	// the paths of the named routes, by name
+	urls map[string]*pathTemplate
 }
 
-var routerInstance *router
+var (
+	routerInstance *router
+	// concurrent first calls have to get the same router
+	routerOnce sync.Once
+)
 
 func Instance() *router {
-	if routerInstance == nil {
-		routerInstance = &router{
-			Routes: make(RoutesMap),
-		}
-	}
+	routerOnce.Do(func() {
+		routerInstance = newRouter()
+	})
 
 	return routerInstance
 }
 
+func newRouter() *router {
+	return &router{
+		Routes:        collections.NewOrderedMap[string, RouteInterface](),
+		order:         ORDER_SPECIFICITY,
+		trailingSlash: TRAILING_SLASH_STRICT,
+		cleanPath:     true,
+		urls:          make(map[string]*pathTemplate),
+	}
+}
+
 // register routes
 func (_router *router) Register(routers ...RouteInterface) *router {
 	return _router.register(routers)
@@ -48,16 +71,8 @@ func (_router *router) Register(routers ...RouteInterface) *router {
 // register a group of routes by defining the group's base path first
 func (_router *router) RegisterGroup(path string, rts ...RouteInterface) *router {
 	for _, rt := range rts {
-		p1 := strings.Split(path, "/")
-		p2 := strings.Split(rt.Path(), "/")
-
-		// remove empty item
-		if p2[0] == "" {
-			p2 = p2[1:]
-		}
-
 		// join the groups path to the route path
-		rt.SetPath(strings.Join(p1, "/") + "/" + strings.Join(p2, "/"))
+		rt.SetPath(joinPaths(path, rt.Path()))
 	}
 
 	return _router.register(rts)
@@ -65,36 +80,74 @@ func (_router *router) RegisterGroup(path string, rts ...RouteInterface) *router
 
 func (_router *router) register(routers []RouteInterface) *router {
 	for _, rt := range routers {
-		ert, ok := _router.Routes[rt.Path()]
+		// routes only get merged when both the path and the matchers are the same
+		ert, ok := _router.Routes.Get(rt.Key())
 		if ok {
-			// merge all the middlewares
-			ert.SetMiddlewares(append(ert.Middlewares(), rt.Middlewares()...))
-
-			ert.SetMethods(append(ert.Methods(), rt.Methods()...))
-
-			for _, method := range rt.Methods() {
-				ert.SetHandler(method, rt.Handler())
-			}
+			// each method keeps its own handler and middlewares
+			rt = ert.Merge(rt)
+		}
+		_router.Routes.Set(rt.Key(), rt)
 
-			_router.Routes[rt.Path()] = ert
-		} else {
-			_router.Routes[rt.Path()] = rt
+		// the first route registered under a name is the one URL() builds
+		if _, ok := _router.urls[rt.Name()]; !ok && rt.Name() != "" {
+			_router.urls[rt.Name()] = newPathTemplate(rt.Path())
 		}
 	}
+	_router.mux = nil
+
+	return _router
+}
+
+// register middlewares that wrap every route, the first one registered is the outermost
+func (_router *router) Use(middlewares ...RouteMiddlewareFunc) *router {
+	_router.middlewares = append(_router.middlewares, middlewares...)
+	_router.mux = nil
+	return _router
+}
 
+// set the handler answering errors like 404 and 405, also used by Error()
+func (_router *router) SetErrorHandler(handler ErrorHandlerFunc) *router {
+	_router.errorHandler = handler
+	_router.mux = nil
 	return _router
 }
 
-func (_router *router) Mux() *mux.Router {
-	_mux := mux.NewRouter()
+// returns the handler serving the registered routes, it is built once and rebuilt
+// only after the routes or the middlewares change
+func (_router *router) Mux() *Mux {
+	if _router.mux == nil {
+		_router.mux = newMux(_router)
+	}
 
-	for _, route := range _router.Routes {
-		handler := route.Apply()
+	return _router.mux
+}
 
-		_mux.Handle(route.Path(), handler)
+// Public: returns the route that matched the request, nil when called outside of the router
+func CurrentRoute(r *http.Request) RouteInterface {
+	match, ok := r.Context().Value(routeKey).(*routeMatch)
+	if !ok {
+		return nil
 	}
 
-	return _mux
+	return match.route
+}
+
+// joins path parts with a single slash between them, the result always starts with a slash
+// and keeps the trailing slash of the last part
+func joinPaths(parts ...string) string {
+	var trimmed []string
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); part != "" {
+			trimmed = append(trimmed, part)
+		}
+	}
+
+	joined := "/" + strings.Join(trimmed, "/")
+	if len(parts) > 0 && len(trimmed) > 0 && strings.HasSuffix(parts[len(parts)-1], "/") {
+		joined += "/"
+	}
+
+	return joined
 }
 
 // ===== ENDOF Router =====
@@ -102,299 +155,314 @@ func (_router *router) Mux() *mux.Router {
 // ===== STARTOF Route =====
 type RouteInterface interface {
 	Methods() []string
-	SetMethods([]string) RouteInterface
 	SetPath(string) RouteInterface
 	Path() string
-	Middlewares() []middleware
-	SetMiddlewares([]middleware) RouteInterface
-	Handler() http.HandlerFunc
-	Handlers() HandlerMap
-	SetHandler(string, http.HandlerFunc) RouteInterface
+	SetName(string) RouteInterface
+	Name() string
+	Handler(string) http.HandlerFunc
+	SetHandler(string, http.HandlerFunc, ...RouteMiddlewareFunc) RouteInterface
+	Middlewares(string) []RouteMiddlewareFunc
+	Use(...RouteMiddlewareFunc) RouteInterface
+	With(...Middleware) RouteInterface
+	Declared(string) []Middleware
+	Merge(RouteInterface) RouteInterface
+	Host(string) RouteInterface
+	Schemes(...string) RouteInterface
+	Headers(...string) RouteInterface
+	Queries(...string) RouteInterface
+	MatcherFunc(func(*http.Request) bool) RouteInterface
+	Match(*http.Request) (map[string]string, bool)
+	Key() string
+	Clone() RouteInterface
 	Apply() http.Handler
 }
 
 type route struct {
-	methods     []string
-	path        string
+	path      string
+	name      string
+	endpoints *collections.OrderedMap[string, *endpoint]
+	matchers  matchers
+}
+
+// the handler and the middleware chain of a single request method on a route
+type endpoint struct {
 	handler     http.HandlerFunc
-	handlers    HandlerMap
-	middlewares []middleware
+	middlewares []RouteMiddlewareFunc
+	// the middlewares passed to the constructor of the route or added with With(), kept to be inspected
+	declared []Middleware
 }
 
-type middleware struct {
-	Methods  []string
-	Function MiddlewareFunc
+// the request methods handled by the route, in the order they were added
+func (_route *route) Methods() []string {
+	return _route.endpoints.Keys()
 }
 
-func (_route *route) SetMiddlewares(m []middleware) RouteInterface {
-	_route.middlewares = m
+func (_route *route) SetPath(p string) RouteInterface {
+	_route.path = p
 	return _route
 }
 
-func (_route *route) Middlewares() []middleware {
-	return _route.middlewares
+func (_route *route) Path() string {
+	return _route.path
 }
 
-func (_route *route) SetMethods(methods []string) RouteInterface {
-	_route.methods = methods
+func (_route *route) SetName(n string) RouteInterface {
+	_route.name = n
 	return _route
 }
 
-func (_route *route) Methods() []string {
-	return _route.methods
+func (_route *route) Name() string {
+	return _route.name
 }
 
-func (_route *route) SetPath(p string) RouteInterface {
-	_route.path = p
+// returns the handler of the method, nil when the method is not handled by the route
+func (_route *route) Handler(method string) http.HandlerFunc {
+	ep, ok := _route.endpoints.Get(method)
+	if !ok {
+		return nil
+	}
+
+	return ep.handler
+}
+
+// sets the handler and the middleware chain of the method, replacing any previous ones
+func (_route *route) SetHandler(method string, h http.HandlerFunc, middlewares ...RouteMiddlewareFunc) RouteInterface {
+	_route.endpoints.Set(method, &endpoint{
+		handler:     h,
+		middlewares: append([]RouteMiddlewareFunc(nil), middlewares...),
+	})
 	return _route
 }
 
-func (_route *route) Path() string {
-	return _route.path
+// returns the middleware chain of the method, the first one is the outermost
+func (_route *route) Middlewares(method string) []RouteMiddlewareFunc {
+	ep, ok := _route.endpoints.Get(method)
+	if !ok {
+		return nil
+	}
+
+	return ep.middlewares
 }
 
-func (_route *route) SetHandler(k string, h http.HandlerFunc) RouteInterface {
-	if _route.handlers == nil {
-		_route.handlers = make(HandlerMap)
+// appends middlewares to the chain of every method currently handled by the route
+func (_route *route) Use(middlewares ...RouteMiddlewareFunc) RouteInterface {
+	for _, ep := range _route.endpoints.Values() {
+		ep.middlewares = append(ep.middlewares, middlewares...)
 	}
-	_route.handlers[k] = h
 	return _route
 }
 
-func (_route *route) Handlers() HandlerMap {
-	return _route.handlers
+// appends middlewares to the chain of every method currently handled by the route like Use(), the
+// middlewares are also kept on the route so that packages can find out what they check, e.g. the
+// permissions a route requires
+func (_route *route) With(middlewares ...Middleware) RouteInterface {
+	for _, ep := range _route.endpoints.Values() {
+		for _, m := range middlewares {
+			ep.middlewares = append(ep.middlewares, m.Wrap)
+		}
+		ep.declared = append(ep.declared, middlewares...)
+	}
+	return _route
 }
 
-func (_route *route) Handler() http.HandlerFunc {
-	return _route.handler
+// returns the middlewares of the method passed to the constructor of the route or added with
+// With(), those of a group come first
+func (_route *route) Declared(method string) []Middleware {
+	ep, ok := _route.endpoints.Get(method)
+	if !ok {
+		return nil
+	}
+
+	return ep.declared
 }
 
-func (_route *route) Apply() http.Handler {
+func (_route *route) declare(method string, declared []Middleware) {
+	if ep, ok := _route.endpoints.Get(method); ok {
+		ep.declared = append([]Middleware(nil), declared...)
+	}
+}
 
-	// wrap the handler function
-	mainHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		for {
-			if isMethodAllowed(req.Method, _route.Methods()) {
-				handler, methodOk := _route.Handlers()[req.Method]
-
-				if methodOk {
-					handler.ServeHTTP(w, req)
-					break
-				} else {
-					_route.Handler().ServeHTTP(w, req)
-					break
-				}
-			}
+// takes over the methods of another route at the same path, a method handled by both routes
+// gets the handler and the middlewares of the other route
+func (_route *route) Merge(other RouteInterface) RouteInterface {
+	for _, method := range other.Methods() {
+		_route.SetHandler(method, other.Handler(method), other.Middlewares(method)...)
+		_route.declare(method, other.Declared(method))
+	}
 
-			var handler http.Handler
+	if _route.name == "" {
+		_route.name = other.Name()
+	}
 
-			if _route.Methods() == nil || len(_route.Methods()) <= 0 {
-				handler = _route.handler
-			}
+	return _route
+}
 
-			if handler != nil {
-				handler.ServeHTTP(w, req)
-				break
-			}
+// returns a copy of the route that can be changed without affecting the original
+func (_route *route) Clone() RouteInterface {
+	clone := &route{
+		path:      _route.path,
+		name:      _route.name,
+		endpoints: collections.NewOrderedMap[string, *endpoint](),
+		matchers: matchers{
+			host:       _route.matchers.host,
+			schemes:    append([]string(nil), _route.matchers.schemes...),
+			headers:    append([][2]string(nil), _route.matchers.headers...),
+			queries:    append([][2]string(nil), _route.matchers.queries...),
+			predicates: append([]predicate(nil), _route.matchers.predicates...),
+		},
+	}
 
-			http.Error(w, "<h1>Method not allowed</h1>", http.StatusMethodNotAllowed)
+	_route.endpoints.Each(func(method string, ep *endpoint) bool {
+		clone.SetHandler(method, ep.handler, ep.middlewares...)
+		clone.declare(method, ep.declared)
+		return true
+	})
 
-			break
-		}
+	return clone
+}
+
+func (_route *route) Apply() http.Handler {
+	allow := strings.Join(_route.Methods(), ", ")
+
+	// answers OPTIONS requests for routes without an OPTIONS handler of their own
+	noContent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
 	})
 
-	handler := http.Handler(mainHandler)
+	// build the chain of every method once, the first middleware is the outermost. Every method
+	// also gets a chain ending with noContent, run for CORS preflight requests asking for the method
+	handlers := make(map[string]http.Handler, _route.endpoints.Len())
+	preflights := make(map[string]http.Handler, _route.endpoints.Len())
+	_route.endpoints.Each(func(method string, ep *endpoint) bool {
+		handlers[method] = chain(ep.handler, ep.middlewares)
+		preflights[method] = chain(noContent, ep.middlewares)
+		return true
+	})
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		for {
-			stop := false
-			for _, _middleware := range _route.middlewares {
-				// when the middleware is allowed for the request method, execute it
-				if isMethodAllowed(r.Method, _middleware.Methods) && !_middleware.Function(w, r) {
-					stop = true // set stop flag to true when a middleware returns false
-					break
-				}
-			}
+		handler, ok := handlers[r.Method]
+		if !ok {
+			handler, ok = handlers[ANY]
+		}
 
-			if stop {
-				// when flag is true do not execute next middlewares and the main handler
-				break
+		if !ok && r.Method == OPTIONS {
+			// the middlewares of the requested method, like a CORS policy, get to answer the preflight
+			if handler, ok = preflights[r.Header.Get("Access-Control-Request-Method")]; !ok {
+				handler, ok = noContent, true
 			}
+		}
 
-			handler.ServeHTTP(w, r)
-			break
+		if !ok {
+			w.Header().Set("Allow", allow)
+			Error(w, r, http.StatusMethodNotAllowed)
+			return
 		}
+
+		handler.ServeHTTP(w, r)
 	})
 }
 
-// Public: Creates a route available for all request method or
-// for a set of specified request method passed through the methods []string parameter
-func Route(methods []string, path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
+// wraps the handler in the middlewares, the first one is the outermost
+func chain(handler http.Handler, middlewares []RouteMiddlewareFunc) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
 	}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{},
-		middlewares: middlewares,
-	}
+	return handler
 }
 
-// Creates a route for the GET request method
-func Get(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  []string{GET},
-			Function: middlewareFunc,
-		})
-	}
-
-	return &route{
-		methods:     []string{GET},
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{GET: handler},
-		middlewares: middlewares,
-	}
+// turns a middleware returning bool into one wrapping the next handler,
+// the next handler is only called when the middleware returns true
+func (m MiddlewareFunc) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
 }
 
-// Public: Creates a route for the POST request method
-func Post(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{POST}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
+func newRoute(methods []string, path string, handler http.HandlerFunc, declared []Middleware) *route {
+	middlewares := make([]RouteMiddlewareFunc, 0, len(declared))
+	for _, m := range declared {
+		middlewares = append(middlewares, m.Wrap)
 	}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{POST: handler},
-		middlewares: middlewares,
+	rt := &route{
+		path:      path,
+		endpoints: collections.NewOrderedMap[string, *endpoint](),
 	}
-}
 
-// Public: Creates a route for the PUT request method
-func Put(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{PUT}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
+	if len(methods) <= 0 {
+		methods = []string{ANY}
 	}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{PUT: handler},
-		middlewares: middlewares,
+	// every method gets its own chain so that adding to one does not leak into the others
+	for _, method := range methods {
+		rt.SetHandler(method, handler, middlewares...)
+		rt.declare(method, declared)
 	}
-}
 
-// Public: Creates a route for the PATCH request method
-func Patch(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{PATCH}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
-	}
+	return rt
+}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{PATCH: handler},
-		middlewares: middlewares,
-	}
+// Public: Creates a route available for all request method or
+// for a set of specified request method passed through the methods []string parameter
+func Route(methods []string, path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute(methods, path, handler, middlewares)
 }
 
-// Public: Creates a route for the HEAD request method
-func Head(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{HEAD}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
-	}
+// Creates a route for the GET request method
+func Get(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{GET}, path, handler, middlewares)
+}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{HEAD: handler},
-		middlewares: middlewares,
-	}
+// Public: Creates a route for the POST request method
+func Post(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{POST}, path, handler, middlewares)
 }
 
-// Public: Creates a route for the DELETE method
-func Delete(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{DELETE}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
-	}
+// Public: Creates a route for the PUT request method
+func Put(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{PUT}, path, handler, middlewares)
+}
 
-	return &route{
-		methods:     methods,
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{DELETE: handler},
-		middlewares: middlewares,
-	}
+// Public: Creates a route for the PATCH request method
+func Patch(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{PATCH}, path, handler, middlewares)
 }
 
-// Public: Creates a route for the OPTIONS request method
-func Options(path string, handler http.HandlerFunc, middlewareFuncs ...MiddlewareFunc) RouteInterface {
-	methods := []string{OPTIONS}
-	middlewares := make([]middleware, len(middlewareFuncs))
-	for _, middlewareFunc := range middlewareFuncs {
-		middlewares = append(middlewares, middleware{
-			Methods:  methods,
-			Function: middlewareFunc,
-		})
-	}
+// Public: Creates a route for the HEAD request method
+func Head(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{HEAD}, path, handler, middlewares)
+}
 
-	return &route{
-		methods:     []string{OPTIONS},
-		path:        path,
-		handler:     handler,
-		handlers:    HandlerMap{OPTIONS: handler},
-		middlewares: middlewares,
-	}
+// Public: Creates a route for the DELETE method
+func Delete(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{DELETE}, path, handler, middlewares)
 }
 
-func isMethodAllowed(requestMethod string, allowedMethods []string) bool {
-	return sliceUtil.Use(allowedMethods).InItems(requestMethod)
+// Public: Creates a route for the OPTIONS request method
+func Options(path string, handler http.HandlerFunc, middlewares ...Middleware) RouteInterface {
+	return newRoute([]string{OPTIONS}, path, handler, middlewares)
 }
 
 // ===== ENDOF Route =====
 
 // ===== TYPES =====
 
+type contextKey int
+
+const (
+	routeKey contextKey = iota
+)
+
 type RouteMiddlewareFunc func(http.Handler) http.Handler
 type MiddlewareFunc func(http.ResponseWriter, *http.Request) bool
-type RoutesMap map[string]RouteInterface
-type HandlerMap map[string]http.HandlerFunc
+
+// a middleware accepted by the route constructors and With(), the route keeps them so that they
+// can be inspected with Declared(). MiddlewareFunc is one
+type Middleware interface {
+	Wrap(http.Handler) http.Handler
+}
+type RoutesMap = collections.OrderedMap[string, RouteInterface]
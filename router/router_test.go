@@ -0,0 +1,295 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// returns a handler writing the given body so tests can tell which route matched
+func respond(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}
+}
+
+func serve(r *router, method string, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.Mux().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestSpecificityOrderShouldPreferStaticSegments(t *testing.T) {
+	r := newRouter().Register(
+		Get("/product/{id}", respond("param")),
+		Get("/product/featured", respond("static")),
+	)
+
+	// run it several times, the order used to depend on map iteration
+	for i := 0; i < 10; i++ {
+		if body := serve(r, GET, "/product/featured").Body.String(); body != "static" {
+			t.Fatalf(`GET /product/featured = %q, want "static"`, body)
+		}
+	}
+
+	if body := serve(r, GET, "/product/1").Body.String(); body != "param" {
+		t.Fatalf(`GET /product/1 = %q, want "param"`, body)
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatalf(`r.Validate() = %v, want nil`, err)
+	}
+}
+
+func TestRegistrationOrderShouldReportShadowedRoutes(t *testing.T) {
+	r := newRouter().SetOrder(ORDER_REGISTRATION).Register(
+		Get("/product/{id}", respond("param")),
+		Get("/product/featured", respond("static")),
+	)
+
+	if body := serve(r, GET, "/product/featured").Body.String(); body != "param" {
+		t.Fatalf(`GET /product/featured = %q, want "param"`, body)
+	}
+
+	var conflict *RouteConflictError
+	if err := r.Validate(); !errors.As(err, &conflict) || conflict.Kind != CONFLICT_SHADOWED || conflict.Route.Path() != "/product/featured" {
+		t.Fatalf(`r.Validate() = %v, want /product/featured to be reported as shadowed`, err)
+	}
+}
+
+func TestValidateShouldReportAmbiguousRoutes(t *testing.T) {
+	r := newRouter().Register(
+		Get("/product/{id}", respond("id")),
+		Get("/product/{slug}", respond("slug")),
+		Get("/product/{id:[0-9]+}/reviews", respond("reviews")),
+		Get("/product/latest/reviews", respond("latest")),
+	)
+
+	var conflict *RouteConflictError
+	err := r.Validate()
+	if !errors.As(err, &conflict) || conflict.Kind != CONFLICT_AMBIGUOUS || conflict.Route.Path() != "/product/{slug}" {
+		t.Fatalf(`r.Validate() = %v, want /product/{slug} to be reported as ambiguous`, err)
+	}
+
+	// the constrained param does not match "latest" so there is no conflict between those two
+	if len(err.(interface{ Unwrap() []error }).Unwrap()) != 1 {
+		t.Fatalf(`r.Validate() = %v, want a single conflict`, err)
+	}
+}
+
+func TestMethodsShouldKeepTheirOwnMiddlewares(t *testing.T) {
+	calls := map[string]int{}
+	count := func(name string) MiddlewareFunc {
+		return func(w http.ResponseWriter, r *http.Request) bool {
+			calls[name]++
+			return true
+		}
+	}
+	deny := MiddlewareFunc(func(w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	})
+
+	r := newRouter().Register(
+		Get("/product", respond("list"), count("get")),
+		Post("/product", respond("create"), deny, count("post")),
+	)
+
+	if rec := serve(r, GET, "/product"); rec.Body.String() != "list" {
+		t.Fatalf(`GET /product = %d %q, want 200 "list"`, rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(r, POST, "/product"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf(`POST /product = %d, want %d`, rec.Code, http.StatusUnauthorized)
+	}
+
+	if calls["get"] != 1 || calls["post"] != 0 {
+		t.Fatalf(`middleware calls = %v, want the GET middleware once and the POST middleware never`, calls)
+	}
+}
+
+func TestRegisteringAMethodTwiceShouldReplaceIt(t *testing.T) {
+	r := newRouter().Register(
+		Get("/product", respond("first")),
+		Post("/product", respond("create")),
+		Get("/product", respond("second")),
+	)
+
+	rt, _ := r.Routes.Get("/product")
+	if methods := rt.Methods(); len(methods) != 2 {
+		t.Fatalf(`rt.Methods() = %v, want [GET POST]`, methods)
+	}
+
+	if body := serve(r, GET, "/product").Body.String(); body != "second" {
+		t.Fatalf(`GET /product = %q, want "second"`, body)
+	}
+}
+
+func TestMethodNotAllowedShouldListAllowedMethods(t *testing.T) {
+	r := newRouter().Register(
+		Get("/product", respond("list")),
+		Put("/product", respond("update")),
+	)
+
+	rec := serve(r, DELETE, "/product")
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, PUT" {
+		t.Fatalf(`DELETE /product = %d with Allow %q, want 405 with "GET, PUT"`, rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestRouteWithoutMethodsShouldHandleAnyMethod(t *testing.T) {
+	called := false
+	r := newRouter().Register(
+		Route(nil, "/webhook", respond("any"), MiddlewareFunc(func(w http.ResponseWriter, r *http.Request) bool {
+			called = true
+			return true
+		})),
+		Post("/webhook", respond("post")),
+	)
+
+	if body := serve(r, PATCH, "/webhook").Body.String(); body != "any" || !called {
+		t.Fatalf(`PATCH /webhook = %q (middleware called: %v), want "any" with the middleware called`, body, called)
+	}
+
+	if body := serve(r, POST, "/webhook").Body.String(); body != "post" {
+		t.Fatalf(`POST /webhook = %q, want "post"`, body)
+	}
+}
+
+func TestURLShouldBuildThePathOfNamedRoutes(t *testing.T) {
+	r := newRouter()
+	r.RegisterGroup("/api",
+		Get("/product/{productId:[0-9]+}", respond("product")).SetName("product.show"),
+		Get("/files/{path...}", respond("files")).SetName("files"),
+	)
+
+	cases := []struct {
+		name  string
+		pairs []string
+		want  string
+	}{
+		{"product.show", []string{"productId", "12"}, "/api/product/12"},
+		{"files", []string{"path", "docs/a b.txt"}, "/api/files/docs/a%20b.txt"},
+		{"files", nil, "/api/files/"},
+	}
+
+	for _, c := range cases {
+		if got, err := r.URL(c.name, c.pairs...); err != nil || got != c.want {
+			t.Fatalf(`URL(%q, %q) = %q, %v, want %q`, c.name, c.pairs, got, err, c.want)
+		}
+	}
+
+	for _, pairs := range [][]string{nil, {"productId", "abc"}} {
+		if _, err := r.URL("product.show", pairs...); err == nil {
+			t.Fatalf(`URL("product.show", %q) error = nil, want an error`, pairs)
+		}
+	}
+
+	if _, err := r.URL("missing"); err == nil {
+		t.Fatalf(`URL("missing") error = nil, want an error`)
+	}
+}
+
+func TestURLShouldNotCompileTheParamPatterns(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+
+	r := newRouter().Register(Get("/product/{productId:[0-9]+}", respond("product")).SetName("product.show"))
+
+	// the values map, the segments and the joined path
+	allocs := testing.AllocsPerRun(100, func() {
+		r.URL("product.show", "productId", "12")
+	})
+
+	if allocs > 4 {
+		t.Fatalf(`URL() allocations = %v, want at most 4`, allocs)
+	}
+}
+
+func TestOptionsShouldBeAnsweredAutomatically(t *testing.T) {
+	var ran []string
+	trace := func(name string) RouteMiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ran = append(ran, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rt := Get("/product", respond("get"))
+	rt.Merge(Post("/product", respond("post")))
+	rt.SetHandler(POST, rt.Handler(POST), trace("post"))
+	r := newRouter().Register(rt)
+
+	rec := serve(r, OPTIONS, "/product")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "GET, POST" || len(ran) != 0 {
+		t.Fatalf(`OPTIONS /product = %d %q, want 204 "GET, POST" without middlewares`, rec.Code, rec.Header().Get("Allow"))
+	}
+
+	req := httptest.NewRequest(OPTIONS, "/product", nil)
+	req.Header.Set("Access-Control-Request-Method", POST)
+	rec = httptest.NewRecorder()
+	r.Mux().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Body.String() != "" || len(ran) != 1 {
+		t.Fatalf(`preflight for POST /product = %d %q after %v, want 204 after the POST middlewares`, rec.Code, rec.Body.String(), ran)
+	}
+
+	r.Register(Options("/product", respond("options")))
+	if body := serve(r, OPTIONS, "/product").Body.String(); body != "options" {
+		t.Fatalf(`OPTIONS /product = %q, want the registered handler`, body)
+	}
+}
+
+func TestRouteGroupShouldApplyPrefixAndMiddlewares(t *testing.T) {
+	var ran []string
+	trace := func(name string) RouteMiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ran = append(ran, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	product := Get("/product/{id}", respond("product"))
+	product.Use(trace("route"))
+
+	group := Group("/api", product).Use(trace("group"))
+	r := newRouter().RegisterGroups(group)
+
+	if body := serve(r, GET, "/api/product/1").Body.String(); body != "product" || strings.Join(ran, ",") != "group,route" {
+		t.Fatalf(`GET /api/product/1 = %q after %v, want "product" after group,route`, body, ran)
+	}
+
+	if product.Path() != "/product/{id}" {
+		t.Fatalf(`original route path = %q, want it untouched`, product.Path())
+	}
+}
+
+func TestDeclaredMiddlewaresShouldBeKeptOnTheRoutes(t *testing.T) {
+	deny := MiddlewareFunc(func(w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	})
+	allow := MiddlewareFunc(func(w http.ResponseWriter, r *http.Request) bool { return true })
+
+	group := Group("/admin",
+		Get("/product", respond("list"), allow),
+		Post("/product", respond("create")).With(deny),
+	).With(allow)
+
+	r := newRouter().RegisterGroups(group)
+
+	if rec := serve(r, POST, "/admin/product"); rec.Code != http.StatusForbidden {
+		t.Fatalf(`POST /admin/product = %d, want 403`, rec.Code)
+	}
+
+	rt, _ := r.Routes.Get(r.Routes.Keys()[0])
+	if get, post := rt.Declared(GET), rt.Declared(POST); len(get) != 2 || len(post) != 2 {
+		t.Fatalf(`Declared() = %d and %d middlewares, want 2 for each method`, len(get), len(post))
+	}
+}
@@ -0,0 +1,289 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cache policy constants, ready to use values for StaticOptions.CacheControl
+const (
+	CACHE_REVALIDATE = "no-cache"                            // cache but check the ETag before every use
+	CACHE_IMMUTABLE  = "public, max-age=31536000, immutable" // for fingerprinted assets like app.3f2a1c.js
+	CACHE_NONE       = "no-store"
+)
+
+const STATIC_INDEX = "index.html"
+
+type StaticOptions struct {
+	// file served for a directory, defaults to STATIC_INDEX
+	Index string
+	// list the content of directories that have no index, they answer with 404 otherwise
+	Browse bool
+	// file served for a missing path without an extension, e.g. index.html for a single page app.
+	// Missing assets like /app.js still answer with 404
+	Fallback string
+	// returns the Cache-Control header of a file, no header is set when nil or when it returns ""
+	CacheControl func(name string) string
+	// serve files and directories starting with a dot, they answer with 404 by default
+	Dotfiles bool
+}
+
+// the encodings of precompressed variants in order of preference, app.js is served from app.js.br
+// or app.js.gz when the variant exists and the client accepts it
+var precompressed = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type staticFiles struct {
+	fsys    fs.FS
+	options StaticOptions
+	// etags of the files without a modification time, like the ones of an embed.FS
+	etags sync.Map
+}
+
+// Public: creates a GET and HEAD route serving the files of the directory under the prefix
+func Static(prefix string, dir string, options ...StaticOptions) RouteInterface {
+	return StaticFS(prefix, os.DirFS(dir), options...)
+}
+
+// Public: creates a GET and HEAD route serving the files of a file system like an embed.FS under the prefix,
+// the path after the prefix is available through Param(r, "filepath")
+func StaticFS(prefix string, fsys fs.FS, options ...StaticOptions) RouteInterface {
+	s := &staticFiles{fsys: fsys}
+	if len(options) > 0 {
+		s.options = options[0]
+	}
+
+	if s.options.Index == "" {
+		s.options.Index = STATIC_INDEX
+	}
+
+	return newRoute([]string{GET, HEAD}, joinPaths(prefix, "*filepath"), s.serve, nil)
+}
+
+func (s *staticFiles) serve(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+Param(r, "filepath")), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !s.options.Dotfiles && hidden(name) {
+		Error(w, r, http.StatusNotFound)
+		return
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil && errors.Is(err, fs.ErrNotExist) && s.options.Fallback != "" && path.Ext(name) == "" {
+		name = s.options.Fallback
+		info, err = fs.Stat(s.fsys, name)
+	}
+
+	if err != nil {
+		Error(w, r, statusOf(err))
+		return
+	}
+
+	if info.IsDir() {
+		s.serveDir(w, r, name)
+		return
+	}
+
+	s.serveFile(w, r, name, info)
+}
+
+func (s *staticFiles) serveDir(w http.ResponseWriter, r *http.Request, name string) {
+	index := path.Join(name, s.options.Index)
+	info, err := fs.Stat(s.fsys, index)
+	hasIndex := err == nil && !info.IsDir()
+
+	if !hasIndex && !s.options.Browse {
+		Error(w, r, http.StatusNotFound)
+		return
+	}
+
+	// relative links of the page only resolve inside the directory with the trailing slash
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		redirect(w, r, r.URL.Path+"/")
+		return
+	}
+
+	if hasIndex {
+		s.serveFile(w, r, index, info)
+		return
+	}
+
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		Error(w, r, statusOf(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html>\n<title>%s</title>\n<ul>\n", html.EscapeString(r.URL.Path))
+	for _, entry := range entries {
+		if !s.options.Dotfiles && hidden(entry.Name()) {
+			continue
+		}
+
+		label := entry.Name()
+		if entry.IsDir() {
+			label += "/"
+		}
+
+		link := url.URL{Path: label}
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(link.String()), html.EscapeString(label))
+	}
+	fmt.Fprint(w, "</ul>\n")
+}
+
+func (s *staticFiles) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	header := w.Header()
+
+	if s.options.CacheControl != nil {
+		if policy := s.options.CacheControl(name); policy != "" {
+			header.Set("Cache-Control", policy)
+		}
+	}
+
+	// the content type comes from the original name, never from the compressed variant
+	contentType := mime.TypeByExtension(path.Ext(name))
+
+	served := name
+	vary := false
+	for _, variant := range precompressed {
+		variantInfo, err := fs.Stat(s.fsys, name+variant.extension)
+		if err != nil || variantInfo.IsDir() {
+			continue
+		}
+
+		if !vary {
+			header.Add("Vary", "Accept-Encoding")
+			vary = true
+		}
+
+		if acceptsEncoding(r, variant.encoding) {
+			served, info = name+variant.extension, variantInfo
+			header.Set("Content-Encoding", variant.encoding)
+			if contentType == "" {
+				// sniffing the compressed bytes would be wrong
+				contentType = "application/octet-stream"
+			}
+			break
+		}
+	}
+
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	f, err := s.fsys.Open(served)
+	if err != nil {
+		Error(w, r, statusOf(err))
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			Error(w, r, http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(served, info, content)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError)
+		return
+	}
+	header.Set("ETag", etag)
+
+	// takes care of If-None-Match, If-Modified-Since and Range requests
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// files with a modification time are tagged by it and their size, the others by a hash of their
+// content which is computed once since such files, like the ones of an embed.FS, do not change
+func (s *staticFiles) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+	s.etags.Store(name, etag)
+
+	return etag, nil
+}
+
+// reports whether the Accept-Encoding header of the request allows the encoding
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+
+		quality, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+
+		q, err := strconv.ParseFloat(quality, 64)
+		return err == nil && q > 0
+	}
+
+	return false
+}
+
+// reports whether any part of the path starts with a dot, like .env or .git/config
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part != "." && strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func assets() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>home</h1>")},
+		"app.js":           {Data: []byte("console.log('app')")},
+		"app.js.gz":        {Data: []byte("gzipped")},
+		"app.js.br":        {Data: []byte("brotli")},
+		"docs/intro.txt":   {Data: []byte("intro")},
+		"docs/.secret.txt": {Data: []byte("secret")},
+		".env":             {Data: []byte("KEY=value")},
+	}
+}
+
+func serveRequest(r *router, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.Mux().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStaticFSShouldServeFiles(t *testing.T) {
+	r := newRouter().Register(StaticFS("/assets", assets()))
+
+	rec := serve(r, GET, "/assets/app.js")
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log('app')" {
+		t.Fatalf(`GET /assets/app.js = %d %q, want 200 "console.log('app')"`, rec.Code, rec.Body.String())
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Fatalf(`Content-Type = %q, want text/javascript`, ct)
+	}
+
+	if body := serve(r, GET, "/assets/").Body.String(); body != "<h1>home</h1>" {
+		t.Fatalf(`GET /assets/ = %q, want the index`, body)
+	}
+
+	for _, path := range []string{"/assets/missing.js", "/assets/.env", "/assets/docs/.secret.txt", "/assets/docs/"} {
+		if rec := serve(r, GET, path); rec.Code != http.StatusNotFound {
+			t.Fatalf(`GET %s = %d, want 404`, path, rec.Code)
+		}
+	}
+
+	if rec := serve(r, POST, "/assets/app.js"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf(`POST /assets/app.js = %d, want 405`, rec.Code)
+	}
+}
+
+func TestStaticFSShouldAnswerConditionalRequests(t *testing.T) {
+	r := newRouter().Register(StaticFS("/assets", assets()))
+
+	etag := serve(r, GET, "/assets/index.html").Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf(`ETag of /assets/index.html is empty, want a content hash`)
+	}
+
+	req := httptest.NewRequest(GET, "/assets/index.html", nil)
+	req.Header.Set("If-None-Match", etag)
+	if rec := serveRequest(r, req); rec.Code != http.StatusNotModified {
+		t.Fatalf(`GET /assets/index.html with If-None-Match = %d, want 304`, rec.Code)
+	}
+
+	req = httptest.NewRequest(GET, "/assets/index.html", nil)
+	req.Header.Set("Range", "bytes=4-7")
+	if rec := serveRequest(r, req); rec.Code != http.StatusPartialContent || rec.Body.String() != "home" {
+		t.Fatalf(`GET /assets/index.html with Range = %d %q, want 206 "home"`, rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticFSShouldServePrecompressedVariants(t *testing.T) {
+	r := newRouter().Register(StaticFS("/assets", assets()))
+
+	cases := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"gzip, deflate, br", "br", "brotli"},
+		{"gzip", "gzip", "gzipped"},
+		{"br;q=0, gzip", "gzip", "gzipped"},
+		{"", "", "console.log('app')"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(GET, "/assets/app.js", nil)
+		req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		rec := serveRequest(r, req)
+
+		if rec.Header().Get("Content-Encoding") != c.encoding || rec.Body.String() != c.body {
+			t.Fatalf(`GET /assets/app.js with Accept-Encoding %q = %q %q, want %q %q`, c.acceptEncoding, rec.Header().Get("Content-Encoding"), rec.Body.String(), c.encoding, c.body)
+		}
+
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+			t.Fatalf(`Content-Type with Accept-Encoding %q = %q, want text/javascript`, c.acceptEncoding, ct)
+		}
+
+		if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Fatalf(`Vary = %q, want "Accept-Encoding"`, vary)
+		}
+	}
+}
+
+func TestStaticFSOptions(t *testing.T) {
+	r := newRouter().Register(StaticFS("/", assets(), StaticOptions{
+		Browse:   true,
+		Fallback: "index.html",
+		CacheControl: func(name string) string {
+			if strings.HasSuffix(name, ".html") {
+				return CACHE_REVALIDATE
+			}
+			return CACHE_IMMUTABLE
+		},
+	}))
+
+	rec := serve(r, GET, "/docs/")
+	if !strings.Contains(rec.Body.String(), `<a href="intro.txt">intro.txt</a>`) || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf(`GET /docs/ = %q, want a listing without hidden files`, rec.Body.String())
+	}
+
+	if rec := serve(r, GET, "/docs"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/docs/" {
+		t.Fatalf(`GET /docs = %d %q, want 301 "/docs/"`, rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = serve(r, GET, "/product/1")
+	if rec.Body.String() != "<h1>home</h1>" || rec.Header().Get("Cache-Control") != CACHE_REVALIDATE {
+		t.Fatalf(`GET /product/1 = %q %q, want the index with %q`, rec.Body.String(), rec.Header().Get("Cache-Control"), CACHE_REVALIDATE)
+	}
+
+	if rec := serve(r, GET, "/missing.js"); rec.Code != http.StatusNotFound {
+		t.Fatalf(`GET /missing.js = %d, want 404`, rec.Code)
+	}
+
+	if cc := serve(r, GET, "/app.js").Header().Get("Cache-Control"); cc != CACHE_IMMUTABLE {
+		t.Fatalf(`Cache-Control of /app.js = %q, want %q`, cc, CACHE_IMMUTABLE)
+	}
+}
+
+func TestStaticShouldServeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRouter().Register(Static("/static/", dir))
+
+	rec := serve(r, GET, "/static/style.css")
+	if rec.Body.String() != "body{}" || rec.Header().Get("Last-Modified") == "" || rec.Header().Get("ETag") == "" {
+		t.Fatalf(`GET /static/style.css = %q with Last-Modified %q and ETag %q, want both headers`, rec.Body.String(), rec.Header().Get("Last-Modified"), rec.Header().Get("ETag"))
+	}
+
+	if rec := serve(r, GET, "/static/../../etc/passwd"); rec.Code != http.StatusMovedPermanently {
+		t.Fatalf(`GET /static/../../etc/passwd = %d, want a redirect to the clean path`, rec.Code)
+	}
+}
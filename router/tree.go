@@ -0,0 +1,306 @@
+package router
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// token kind constants used when splitting a route path for the tree
+const (
+	tokenStatic = iota
+	tokenParam
+	tokenCatchAll
+)
+
+type token struct {
+	kind    int
+	text    string // the static text or the param name
+	pattern string // the pattern of a constrained param, empty otherwise
+}
+
+// a node of the radix tree, static children share their common prefixes while params and
+// catch-alls hang off the node where their segment starts
+type node struct {
+	prefix   string
+	indices  string // first byte of every static child, in the same order as children
+	children []*node
+	params   []*node // constrained params first, the unconstrained one last
+	catchAll *node
+	pattern  *regexp.Regexp
+	leaf     *leaf
+}
+
+// the routes ending at a node, tried in order until one of them matches the request
+type leaf struct {
+	entries []*leafEntry
+}
+
+type leafEntry struct {
+	index   int // position in the ordered routes, used when the first registered route wins
+	route   RouteInterface
+	handler http.Handler
+	names   []string // param names in the order their values are captured
+}
+
+// the state of a single lookup, reused through a pool so that matching does not allocate
+type search struct {
+	request *http.Request
+	// the path as requested, params are read from it when the tree is walked with a lowercased path
+	raw          string
+	values       []string
+	registration bool
+	best         *leafEntry
+	bestValues   []string
+	// the params captured from the host by the best entry, so that its matchers run only once
+	bestHost map[string]string
+}
+
+// splits a route path into static text, params and a trailing catch-all
+func tokenize(path string) []token {
+	var tokens []token
+	var static strings.Builder
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if i > 0 {
+			static.WriteByte('/')
+		}
+
+		kind, name, pattern := segmentKind(seg)
+		if kind == tokenStatic {
+			static.WriteString(seg)
+			continue
+		}
+
+		if static.Len() > 0 {
+			tokens = append(tokens, token{kind: tokenStatic, text: static.String()})
+			static.Reset()
+		}
+		tokens = append(tokens, token{kind: kind, text: name, pattern: pattern})
+
+		// nothing can follow a catch-all
+		if kind == tokenCatchAll {
+			return tokens
+		}
+	}
+
+	if static.Len() > 0 {
+		tokens = append(tokens, token{kind: tokenStatic, text: static.String()})
+	}
+
+	return tokens
+}
+
+// tells whether a path segment is static text, a {param} or a catch-all written as *name or {name...}.
+// An anonymous catch-all (* or {...}) is available under the name "*"
+func segmentKind(seg string) (int, string, string) {
+	if strings.HasPrefix(seg, "*") {
+		return tokenCatchAll, catchAllName(seg[1:]), ""
+	}
+
+	if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+		return tokenStatic, seg, ""
+	}
+
+	inner := seg[1 : len(seg)-1]
+	if strings.HasSuffix(inner, "...") {
+		return tokenCatchAll, catchAllName(strings.TrimSuffix(inner, "...")), ""
+	}
+
+	name, pattern, _ := strings.Cut(inner, ":")
+
+	return tokenParam, name, pattern
+}
+
+func catchAllName(name string) string {
+	if name == "" {
+		return "*"
+	}
+
+	return name
+}
+
+// adds the route to the tree, routes at the same node are kept in the order they are inserted
+func (n *node) insert(path string, entry *leafEntry, caseInsensitive bool) {
+	current := n
+	for _, t := range tokenize(path) {
+		switch t.kind {
+		case tokenStatic:
+			if caseInsensitive {
+				t.text = lowerASCII(t.text)
+			}
+			current = current.staticChild(t.text)
+		case tokenParam:
+			current = current.paramChild(t.pattern)
+			entry.names = append(entry.names, t.text)
+		case tokenCatchAll:
+			if current.catchAll == nil {
+				current.catchAll = &node{}
+			}
+			current = current.catchAll
+			entry.names = append(entry.names, t.text)
+		}
+	}
+
+	if current.leaf == nil {
+		current.leaf = &leaf{}
+	}
+	current.leaf.entries = append(current.leaf.entries, entry)
+}
+
+// returns the node reached after the static text, splitting existing nodes where needed
+func (n *node) staticChild(text string) *node {
+	if text == "" {
+		return n
+	}
+
+	i := strings.IndexByte(n.indices, text[0])
+	if i < 0 {
+		child := &node{prefix: text}
+		n.indices += text[:1]
+		n.children = append(n.children, child)
+		return child
+	}
+
+	child := n.children[i]
+	common := commonPrefix(child.prefix, text)
+
+	if common < len(child.prefix) {
+		// move what is left of the child one level down
+		rest := *child
+		rest.prefix = child.prefix[common:]
+		*child = node{
+			prefix:   child.prefix[:common],
+			indices:  rest.prefix[:1],
+			children: []*node{&rest},
+		}
+	}
+
+	return child.staticChild(text[common:])
+}
+
+func (n *node) paramChild(pattern string) *node {
+	for _, p := range n.params {
+		if (p.pattern == nil && pattern == "") || (p.pattern != nil && p.pattern.String() == "^(?:"+pattern+")$") {
+			return p
+		}
+	}
+
+	child := &node{}
+	if pattern == "" {
+		n.params = append(n.params, child)
+		return child
+	}
+
+	child.pattern = regexp.MustCompile("^(?:" + pattern + ")$")
+
+	// constrained params are tried before the unconstrained one
+	position := len(n.params)
+	if position > 0 && n.params[position-1].pattern == nil {
+		position--
+	}
+	n.params = append(n.params[:position], append([]*node{child}, n.params[position:]...)...)
+
+	return child
+}
+
+// walks the tree for the path, the prefix of n has already been consumed.
+// Returns true when the search is over
+func (n *node) lookup(path string, s *search) bool {
+	if path == "" && n.leaf != nil && s.visit(n.leaf) {
+		return true
+	}
+
+	if path != "" {
+		// static text has the highest priority
+		if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+			child := n.children[i]
+			if strings.HasPrefix(path, child.prefix) && child.lookup(path[len(child.prefix):], s) {
+				return true
+			}
+		}
+
+		// then a param spanning the next segment
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+
+		if end > 0 {
+			// the path is always a suffix of the raw path, which keeps the original case
+			value := s.raw[len(s.raw)-len(path):][:end]
+			for _, p := range n.params {
+				if p.pattern != nil && !p.pattern.MatchString(value) {
+					continue
+				}
+
+				s.values = append(s.values, value)
+				if p.lookup(path[end:], s) {
+					return true
+				}
+				s.values = s.values[:len(s.values)-1]
+			}
+		}
+	}
+
+	// and a catch-all taking whatever is left
+	if n.catchAll != nil && n.catchAll.leaf != nil {
+		s.values = append(s.values, s.raw[len(s.raw)-len(path):])
+		if s.visit(n.catchAll.leaf) {
+			return true
+		}
+		s.values = s.values[:len(s.values)-1]
+	}
+
+	return false
+}
+
+// looks for an entry matching the request, returns true when the search can stop
+func (s *search) visit(l *leaf) bool {
+	for _, entry := range l.entries {
+		if s.best != nil && entry.index >= s.best.index {
+			continue
+		}
+
+		host, ok := entry.route.Match(s.request)
+		if !ok {
+			continue
+		}
+
+		s.best = entry
+		s.bestHost = host
+		s.bestValues = append(s.bestValues[:0], s.values...)
+
+		// in registration order an entry found later might still have been registered earlier
+		return !s.registration
+	}
+
+	return false
+}
+
+func commonPrefix(a string, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+// lowercases ASCII letters only so that the byte offsets stay the same as in the original
+func lowerASCII(text string) string {
+	for i := 0; i < len(text); i++ {
+		if 'A' <= text[i] && text[i] <= 'Z' {
+			lowered := []byte(text)
+			for j := i; j < len(lowered); j++ {
+				if 'A' <= lowered[j] && lowered[j] <= 'Z' {
+					lowered[j] += 'a' - 'A'
+				}
+			}
+			return string(lowered)
+		}
+	}
+
+	return text
+}
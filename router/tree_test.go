@@ -0,0 +1,206 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// returns a handler writing the route path followed by its params
+func echoParams(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(CurrentRoute(r).Path()))
+	for _, p := range r.Context().Value(routeKey).(*routeMatch).params {
+		w.Write([]byte(" " + p.key + "=" + p.value))
+	}
+}
+
+func TestTreeMatching(t *testing.T) {
+	r := newRouter().Register(
+		Get("/", echoParams),
+		Get("/product", echoParams),
+		Get("/products", echoParams),
+		Get("/product/{productId}", echoParams),
+		Get("/product/{productId:[0-9]+}/reviews", echoParams),
+		Get("/product/{slug}/reviews", echoParams),
+		Get("/product/featured/top", echoParams),
+		Get("/category/{category}/product/{productId}", echoParams),
+		Get("/files/*filepath", echoParams),
+	)
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/", "/"},
+		{"/product", "/product"},
+		{"/products", "/products"},
+		{"/product/1", "/product/{productId} productId=1"},
+		{"/product/1/reviews", "/product/{productId:[0-9]+}/reviews productId=1"},
+		{"/product/shoe/reviews", "/product/{slug}/reviews slug=shoe"},
+		{"/product/featured/top", "/product/featured/top"},
+		// the static branch "featured" does not lead anywhere, the param branch takes over
+		{"/product/featured/reviews", "/product/{slug}/reviews slug=featured"},
+		{"/category/shoes/product/2", "/category/{category}/product/{productId} category=shoes productId=2"},
+		{"/files/css/app.css", "/files/*filepath filepath=css/app.css"},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, GET, c.path).Body.String(); body != c.want {
+			t.Fatalf(`GET %s = %q, want %q`, c.path, body, c.want)
+		}
+	}
+
+	for _, path := range []string{"/prod", "/product/1/reviews/2", "/unknown"} {
+		if rec := serve(r, GET, path); rec.Code != http.StatusNotFound {
+			t.Fatalf(`GET %s = %d, want %d`, path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestParamAccessors(t *testing.T) {
+	var params map[string]string
+	var productId string
+
+	r := newRouter().Register(
+		Get("/category/{category}/product/{productId}", func(w http.ResponseWriter, r *http.Request) {
+			params = Params(r)
+			productId = Param(r, "productId")
+		}),
+	)
+
+	serve(r, GET, "/category/shoes/product/7")
+
+	if params["category"] != "shoes" || params["productId"] != "7" || productId != "7" {
+		t.Fatalf(`Params(r) = %v, Param(r, "productId") = %q, want category shoes and productId 7`, params, productId)
+	}
+}
+
+func TestErrorHandlerShouldAnswerErrors(t *testing.T) {
+	var statuses []int
+	r := newRouter().
+		SetErrorHandler(func(w http.ResponseWriter, r *http.Request, status int) {
+			statuses = append(statuses, status)
+			w.WriteHeader(status)
+		}).
+		Register(Get("/product", respond("list")))
+
+	serve(r, GET, "/unknown")
+	serve(r, POST, "/product")
+
+	if len(statuses) != 2 || statuses[0] != http.StatusNotFound || statuses[1] != http.StatusMethodNotAllowed {
+		t.Fatalf(`error handler statuses = %v, want [404 405]`, statuses)
+	}
+}
+
+func TestMuxShouldBeRebuiltOnlyAfterChanges(t *testing.T) {
+	r := newRouter().Register(Get("/product", respond("list")))
+
+	if r.Mux() != r.Mux() {
+		t.Fatalf(`r.Mux() should return the same handler while nothing changes`)
+	}
+
+	before := r.Mux()
+	r.Register(Get("/category", respond("list")))
+
+	if r.Mux() == before {
+		t.Fatalf(`r.Mux() should be rebuilt after registering a route`)
+	}
+}
+
+func TestLookupShouldNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+
+	m := newRouter().Register(
+		Get("/product/{productId}", respond("product")),
+		Get("/category/{category}/product/{productId}", respond("product")),
+	).Mux()
+
+	req := httptest.NewRequest(GET, "/category/shoes/product/7", nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.release(m.find(req, req.URL.Path))
+	})
+
+	if allocs != 0 {
+		t.Fatalf(`lookup allocations = %v, want 0`, allocs)
+	}
+}
+
+// serving only copies the request to carry the match, and allocates the match itself
+func TestServeShouldAllocateTwice(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+
+	m := newRouter().Register(
+		Get("/category/{category}/product/{productId}", func(w http.ResponseWriter, r *http.Request) {}),
+	).Mux()
+
+	req := httptest.NewRequest(GET, "/category/shoes/product/7", nil)
+	w := httptest.NewRecorder()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.ServeHTTP(w, req)
+	})
+
+	if allocs > 2 {
+		t.Fatalf(`serve allocations = %v, want at most 2`, allocs)
+	}
+}
+
+func TestCatchAllPrecedence(t *testing.T) {
+	r := newRouter().Register(
+		Get("/*path", echoParams),
+		Get("/static/*filepath", echoParams),
+		Get("/docs/{rest...}", echoParams),
+		Get("/docs/{page}", echoParams),
+		Get("/docs/index", echoParams),
+		Get("/api/product/{productId}", echoParams),
+		Get("/api/*", echoParams),
+	)
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/static/css/app.css", "/static/*filepath filepath=css/app.css"},
+		{"/static/", "/static/*filepath filepath="},
+		{"/docs/index", "/docs/index"},
+		{"/docs/intro", "/docs/{page} page=intro"},
+		{"/docs/guide/routing", "/docs/{rest...} rest=guide/routing"},
+		{"/api/product/1", "/api/product/{productId} productId=1"},
+		// the api catch-all keeps unknown api paths away from the SPA fallback
+		{"/api/product/1/unknown", "/api/* *=product/1/unknown"},
+		{"/", "/*path path="},
+		{"/account/settings", "/*path path=account/settings"},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, GET, c.path).Body.String(); body != c.want {
+			t.Fatalf(`GET %s = %q, want %q`, c.path, body, c.want)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatalf(`r.Validate() = %v, want nil`, err)
+	}
+}
+
+func TestValidateShouldReportCatchAllConflicts(t *testing.T) {
+	r := newRouter().SetOrder(ORDER_REGISTRATION).Register(
+		Get("/files/*filepath", echoParams),
+		Get("/files/{name}", echoParams),
+		Get("/docs/{rest...}/edit", echoParams),
+	)
+
+	err := r.Validate()
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Fatalf(`r.Validate() = %v, want the misplaced catch-all and the shadowed route`, err)
+	}
+
+	if body := serve(r, GET, "/files/readme").Body.String(); body != "/files/*filepath filepath=readme" {
+		t.Fatalf(`GET /files/readme = %q, want the catch-all registered first`, body)
+	}
+}
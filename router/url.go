@@ -0,0 +1,98 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// a route path split into segments with the patterns of its params compiled, built once when
+// the route is registered so that building urls does not compile them again
+type pathTemplate struct {
+	path     string
+	segments []pathSegment
+}
+
+type pathSegment struct {
+	kind    int
+	text    string // the segment itself for static segments, the param name otherwise
+	pattern string
+	matcher *regexp.Regexp
+}
+
+func newPathTemplate(routePath string) *pathTemplate {
+	t := &pathTemplate{path: routePath}
+	for _, seg := range strings.Split(routePath, "/") {
+		kind, name, pattern := segmentKind(seg)
+		if kind == tokenStatic {
+			t.segments = append(t.segments, pathSegment{kind: kind, text: seg})
+			continue
+		}
+
+		s := pathSegment{kind: kind, text: name, pattern: pattern}
+		if pattern != "" {
+			s.matcher = regexp.MustCompile("^(?:" + pattern + ")$")
+		}
+		t.segments = append(t.segments, s)
+	}
+
+	return t
+}
+
+// builds the path of the route with the given name, params are given as key/value pairs:
+// URL("product.show", "productId", "1") returns /api/product/1
+func (_router *router) URL(name string, pairs ...string) (string, error) {
+	if t, ok := _router.urls[name]; ok {
+		return t.build(toPairs(pairs))
+	}
+
+	// routes named after they were registered
+	for _, rt := range _router.Routes.Values() {
+		if rt.Name() == name {
+			return newPathTemplate(rt.Path()).build(toPairs(pairs))
+		}
+	}
+
+	return "", fmt.Errorf("router: there is no route named %q", name)
+}
+
+// fills the params of the path, values are escaped and have to match the pattern of their param
+func (t *pathTemplate) build(pairs [][2]string) (string, error) {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		values[pair[0]] = pair[1]
+	}
+
+	segments := make([]string, len(t.segments))
+	for i, seg := range t.segments {
+		if seg.kind == tokenStatic {
+			segments[i] = seg.text
+			continue
+		}
+
+		// a catch-all may be empty, a param may not
+		value, ok := values[seg.text]
+		if !ok && seg.kind == tokenParam {
+			return "", fmt.Errorf("router: missing param %q to build the path of %q", seg.text, t.path)
+		}
+
+		if seg.matcher != nil && !seg.matcher.MatchString(value) {
+			return "", fmt.Errorf("router: param %q of %q does not match %q", seg.text, t.path, seg.pattern)
+		}
+
+		if seg.kind == tokenCatchAll {
+			// the slashes of a catch-all value are kept
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+			continue
+		}
+
+		segments[i] = url.PathEscape(value)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,265 @@
+package router
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waponix/netgo/utils/sliceUtil"
+)
+
+// version selection strategy constants
+const (
+	VERSION_PATH   = "PATH"   // the version is part of the path: /api/v2/product
+	VERSION_ACCEPT = "ACCEPT" // vendor media type: Accept: application/vnd.netgo.v2+json
+	VERSION_HEADER = "HEADER" // custom header: X-API-Version: 2
+)
+
+const (
+	DEFAULT_VERSION_HEADER = "X-API-Version"
+	DEFAULT_VENDOR         = "netgo"
+)
+
+type VersioningOptions struct {
+	// how the client picks a version, ACCEPT and HEADER are tried in the given order and
+	// fall back to the latest version when the request does not ask for one. Defaults to VERSION_PATH
+	Strategies []string
+	Vendor     string
+	// header read by VERSION_HEADER, the served version is echoed back in it for every strategy
+	Header string
+}
+
+// routes of an API served side by side in several versions
+type VersionGroup struct {
+	prefix   string
+	options  VersioningOptions
+	accept   *regexp.Regexp
+	versions []*APIVersion
+}
+
+type APIVersion struct {
+	number       int
+	routes       []RouteInterface
+	deprecatedAt time.Time
+	sunsetAt     time.Time
+	link         string
+}
+
+// Public: creates a group of versioned routes under the prefix, register it with RegisterVersions()
+func Versioned(prefix string, options VersioningOptions) *VersionGroup {
+	if len(options.Strategies) <= 0 {
+		options.Strategies = []string{VERSION_PATH}
+	}
+
+	if options.Vendor == "" {
+		options.Vendor = DEFAULT_VENDOR
+	}
+
+	if options.Header == "" {
+		options.Header = DEFAULT_VERSION_HEADER
+	}
+
+	return &VersionGroup{
+		prefix:  prefix,
+		options: options,
+		accept:  regexp.MustCompile(`vnd\.` + regexp.QuoteMeta(options.Vendor) + `\.v(\d+)`),
+	}
+}
+
+// adds routes to a version, a route missing from a version is served by the closest older version having it
+func (g *VersionGroup) Version(number int, rts ...RouteInterface) *APIVersion {
+	for _, v := range g.versions {
+		if v.number == number {
+			v.routes = append(v.routes, rts...)
+			return v
+		}
+	}
+
+	v := &APIVersion{number: number, routes: rts}
+	g.versions = append(g.versions, v)
+	sort.Slice(g.versions, func(i, j int) bool {
+		return g.versions[i].number < g.versions[j].number
+	})
+
+	return v
+}
+
+// marks the version as deprecated since the given time, responses get a Deprecation header
+func (v *APIVersion) Deprecate(at time.Time) *APIVersion {
+	v.deprecatedAt = at
+	return v
+}
+
+// announces when the version stops being served through the Sunset header, afterwards its routes
+// answer with 410 Gone. The optional link points clients to the migration documentation
+func (v *APIVersion) Sunset(at time.Time, link string) *APIVersion {
+	v.sunsetAt = at
+	v.link = link
+	return v
+}
+
+// builds the routes of every version according to the strategies
+func (g *VersionGroup) Routes() []RouteInterface {
+	var rts []RouteInterface
+
+	// versions defining each path, in ascending order
+	var paths []string
+	defining := make(map[string][]*APIVersion)
+	for _, v := range g.versions {
+		for _, rt := range v.routes {
+			versions := defining[rt.Path()]
+			if len(versions) <= 0 {
+				paths = append(paths, rt.Path())
+			}
+			if len(versions) <= 0 || versions[len(versions)-1] != v {
+				defining[rt.Path()] = append(versions, v)
+			}
+		}
+	}
+
+	if g.uses(VERSION_PATH) {
+		for _, requested := range g.versions {
+			for _, path := range paths {
+				served := closestVersion(defining[path], requested.number)
+				if served == nil {
+					continue
+				}
+
+				for _, rt := range served.routesAt(path) {
+					clone := rt.Clone()
+					clone.SetPath(joinPaths(g.prefix, "v"+strconv.Itoa(requested.number), path))
+					clone.Use(g.versionMiddleware(requested, nil))
+					rts = append(rts, clone)
+				}
+			}
+		}
+	}
+
+	if g.uses(VERSION_ACCEPT) || g.uses(VERSION_HEADER) {
+		// the same url answers with another version depending on these headers, shared caches have
+		// to keep a response per value
+		var vary []string
+		for _, strategy := range g.options.Strategies {
+			switch strategy {
+			case VERSION_ACCEPT:
+				vary = append(vary, "Accept")
+			case VERSION_HEADER:
+				vary = append(vary, g.options.Header)
+			}
+		}
+
+		for _, path := range paths {
+			versions := defining[path]
+			for _, served := range versions {
+				number := served.number
+				name := "version=" + g.prefix + ":" + strconv.Itoa(number)
+				matches := func(r *http.Request) bool {
+					closest := closestVersion(versions, g.requestedVersion(r))
+					return closest != nil && closest.number == number
+				}
+
+				for _, rt := range served.routesAt(path) {
+					clone := rt.Clone().SetPath(joinPaths(g.prefix, path)).(*route).condition(name, matches)
+					// the clone only matches requests it serves, its version is the one to report
+					clone.Use(g.versionMiddleware(served, vary))
+					rts = append(rts, clone)
+				}
+			}
+		}
+	}
+
+	return rts
+}
+
+// register the routes of versioned groups
+func (_router *router) RegisterVersions(groups ...*VersionGroup) *router {
+	for _, g := range groups {
+		_router.register(g.Routes())
+	}
+
+	return _router
+}
+
+func (g *VersionGroup) uses(strategy string) bool {
+	return sliceUtil.Contains(g.options.Strategies, strategy)
+}
+
+// the version asked for by the request, the latest version when it does not ask for one
+func (g *VersionGroup) requestedVersion(r *http.Request) int {
+	for _, strategy := range g.options.Strategies {
+		switch strategy {
+		case VERSION_ACCEPT:
+			if match := g.accept.FindStringSubmatch(r.Header.Get("Accept")); match != nil {
+				if number, err := strconv.Atoi(match[1]); err == nil {
+					return number
+				}
+			}
+		case VERSION_HEADER:
+			value := strings.TrimPrefix(strings.ToLower(r.Header.Get(g.options.Header)), "v")
+			if number, err := strconv.Atoi(value); err == nil {
+				return number
+			}
+		}
+	}
+
+	return g.versions[len(g.versions)-1].number
+}
+
+// adds the version headers to the response and refuses requests to versions past their sunset,
+// vary lists the request headers the version was chosen by
+func (g *VersionGroup) versionMiddleware(v *APIVersion, vary []string) RouteMiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, header := range vary {
+				w.Header().Add("Vary", header)
+			}
+
+			w.Header().Set(g.options.Header, strconv.Itoa(v.number))
+
+			if !v.deprecatedAt.IsZero() {
+				// RFC 9745 structured date
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(v.deprecatedAt.Unix(), 10))
+			}
+
+			if !v.sunsetAt.IsZero() {
+				w.Header().Set("Sunset", v.sunsetAt.UTC().Format(http.TimeFormat))
+				if v.link != "" {
+					w.Header().Add("Link", "<"+v.link+`>; rel="sunset"`)
+				}
+
+				if !time.Now().Before(v.sunsetAt) {
+					Error(w, r, http.StatusGone)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (v *APIVersion) routesAt(path string) []RouteInterface {
+	var rts []RouteInterface
+	for _, rt := range v.routes {
+		if rt.Path() == path {
+			rts = append(rts, rt)
+		}
+	}
+
+	return rts
+}
+
+// the newest of the versions that is not newer than the requested number
+func closestVersion(versions []*APIVersion, requested int) *APIVersion {
+	var closest *APIVersion
+	for _, v := range versions {
+		if v.number <= requested {
+			closest = v
+		}
+	}
+
+	return closest
+}
@@ -0,0 +1,139 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func versionedProducts(strategies ...string) *router {
+	api := Versioned("/api", VersioningOptions{Strategies: strategies})
+	api.Version(1,
+		Get("/product/{productId}", respond("v1 product")),
+		Get("/category", respond("v1 category")),
+	).Deprecate(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).Sunset(time.Now().Add(time.Hour), "https://example.com/migrate")
+	api.Version(2,
+		Get("/product/{productId}", respond("v2 product")),
+		Post("/product/{productId}", respond("v2 update")),
+	)
+
+	return newRouter().RegisterVersions(api)
+}
+
+func TestPathVersioning(t *testing.T) {
+	r := versionedProducts(VERSION_PATH)
+
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{GET, "/api/v1/product/1", "v1 product"},
+		{GET, "/api/v2/product/1", "v2 product"},
+		{POST, "/api/v2/product/1", "v2 update"},
+		// v2 does not define the category route, it falls back to v1
+		{GET, "/api/v2/category", "v1 category"},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, c.method, c.path).Body.String(); body != c.want {
+			t.Fatalf(`%s %s = %q, want %q`, c.method, c.path, body, c.want)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatalf(`r.Validate() = %v, want nil`, err)
+	}
+}
+
+func TestHeaderAndAcceptVersioning(t *testing.T) {
+	r := versionedProducts(VERSION_ACCEPT, VERSION_HEADER)
+
+	cases := []struct {
+		header string
+		value  string
+		want   string
+	}{
+		{"", "", "v2 product"},
+		{"Accept", "application/vnd.netgo.v1+json", "v1 product"},
+		{DEFAULT_VERSION_HEADER, "1", "v1 product"},
+		// there is no v5 yet, the latest compatible version answers
+		{DEFAULT_VERSION_HEADER, "v5", "v2 product"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(GET, "/api/product/1", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		rec := httptest.NewRecorder()
+		r.Mux().ServeHTTP(rec, req)
+
+		if rec.Body.String() != c.want {
+			t.Fatalf(`GET /api/product/1 with %s %q = %q, want %q`, c.header, c.value, rec.Body.String(), c.want)
+		}
+	}
+}
+
+func TestDeprecatedVersionHeaders(t *testing.T) {
+	r := versionedProducts(VERSION_PATH)
+
+	rec := serve(r, GET, "/api/v1/product/1")
+	if rec.Header().Get("Deprecation") != "@1704067200" || rec.Header().Get("Sunset") == "" || rec.Header().Get("Link") != `<https://example.com/migrate>; rel="sunset"` {
+		t.Fatalf(`GET /api/v1/product/1 headers = %v, want Deprecation, Sunset and Link`, rec.Header())
+	}
+
+	if rec := serve(r, GET, "/api/v2/product/1"); rec.Header().Get("Deprecation") != "" || rec.Header().Get(DEFAULT_VERSION_HEADER) != "2" {
+		t.Fatalf(`GET /api/v2/product/1 headers = %v, want no Deprecation and version 2`, rec.Header())
+	}
+}
+
+func TestRetiredVersionShouldBeGone(t *testing.T) {
+	api := Versioned("/api", VersioningOptions{})
+	api.Version(1, Get("/product", respond("v1"))).Sunset(time.Now().Add(-time.Hour), "")
+
+	rec := serve(newRouter().RegisterVersions(api), GET, "/api/v1/product")
+	if rec.Code != http.StatusGone {
+		t.Fatalf(`GET /api/v1/product = %d, want %d`, rec.Code, http.StatusGone)
+	}
+}
+
+func TestHeaderVersioningShouldReportTheServedVersion(t *testing.T) {
+	r := versionedProducts(VERSION_HEADER)
+
+	req := httptest.NewRequest(GET, "/api/category", nil)
+	req.Header.Set(DEFAULT_VERSION_HEADER, "2")
+	rec := httptest.NewRecorder()
+	r.Mux().ServeHTTP(rec, req)
+
+	// v2 has no category route, v1 answers and says so
+	if rec.Body.String() != "v1 category" || rec.Header().Get(DEFAULT_VERSION_HEADER) != "1" || rec.Header().Get("Deprecation") == "" {
+		t.Fatalf(`GET /api/category with version 2 = %q %v, want v1 category with the v1 headers`, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestRetiredVersionShouldUseTheErrorHandler(t *testing.T) {
+	api := Versioned("/api", VersioningOptions{})
+	api.Version(1, Get("/product", respond("v1"))).Sunset(time.Now().Add(-time.Hour), "")
+
+	r := newRouter().RegisterVersions(api).SetErrorHandler(func(w http.ResponseWriter, r *http.Request, status int) {
+		w.WriteHeader(status)
+		w.Write([]byte("custom"))
+	})
+
+	if rec := serve(r, GET, "/api/v1/product"); rec.Code != http.StatusGone || rec.Body.String() != "custom" {
+		t.Fatalf(`GET /api/v1/product = %d %q, want %d "custom"`, rec.Code, rec.Body.String(), http.StatusGone)
+	}
+}
+
+func TestHeaderAndAcceptVersioningShouldVary(t *testing.T) {
+	rec := serve(versionedProducts(VERSION_ACCEPT, VERSION_HEADER), GET, "/api/product/1")
+	if vary := rec.Header().Values("Vary"); len(vary) != 2 || vary[0] != "Accept" || vary[1] != DEFAULT_VERSION_HEADER {
+		t.Fatalf(`GET /api/product/1 Vary = %v, want Accept and %s`, vary, DEFAULT_VERSION_HEADER)
+	}
+
+	if rec := serve(versionedProducts(VERSION_PATH), GET, "/api/v1/product/1"); rec.Header().Get("Vary") != "" {
+		t.Fatalf(`GET /api/v1/product/1 Vary = %v, want none`, rec.Header().Values("Vary"))
+	}
+}
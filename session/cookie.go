@@ -0,0 +1,51 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/waponix/netgo/cookie"
+)
+
+// keeps the sessions in the cookie itself, signed or encrypted depending on the codec. Nothing is
+// kept on the server: Delete() can not revoke a copy of the cookie before it expires
+type CookieStore struct {
+	codec *cookie.Codec
+	// the values are bound to the name of the cookie they are sent in, set by NewManager()
+	name string
+}
+
+// Public: creates a CookieStore protecting the sessions with the codec, use cookie.Encrypted()
+// unless the client may read the values
+func NewCookieStore(codec *cookie.Codec) *CookieStore {
+	return &CookieStore{codec: codec, name: DEFAULT_COOKIE_NAME}
+}
+
+func (s *CookieStore) Load(token string) (*Record, error) {
+	data, err := s.codec.Open(s.name, token)
+	if errors.Is(err, cookie.ErrInvalid) || errors.Is(err, cookie.ErrExpired) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRecord(data)
+}
+
+func (s *CookieStore) Save(record *Record) (string, error) {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return "", err
+	}
+
+	return s.codec.Seal(s.name, data)
+}
+
+func (s *CookieStore) Delete(id string) error {
+	return nil
+}
+
+func (s *CookieStore) GC(now time.Time) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const FILE_EXTENSION = ".session"
+
+// keeps every session in a file of its own in a directory
+type FileStore struct {
+	dir string
+}
+
+// Public: creates a FileStore keeping the sessions in the directory, which is created when missing
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Load(token string) (*Record, error) {
+	path, err := s.path(token)
+	if err != nil {
+		// a forged cookie is not an error worth reporting, there is simply no such session
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRecord(data)
+}
+
+func (s *FileStore) Save(record *Record) (string, error) {
+	path, err := s.path(record.ID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := encodeRecord(record)
+	if err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so that a concurrent Load never reads half a session
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) GC(now time.Time) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), FILE_EXTENSION) {
+			continue
+		}
+
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		// a file that can not be read or decoded right now is reported and left alone, only
+		// sessions known to be expired are removed
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		record, err := decodeRecord(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+
+		if !now.Before(record.Expires) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// the file of the session, ids are checked so that they can never point outside of the directory
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.Trim(id, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_") != "" {
+		return "", ErrInvalidID
+	}
+
+	return filepath.Join(s.dir, id+FILE_EXTENSION), nil
+}
@@ -0,0 +1,357 @@
+// Package session keeps per-client state between requests. The middleware of a Manager loads the
+// session of the request before the handler runs and saves it before the response is written.
+package session
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/waponix/netgo/cookie"
+	"github.com/waponix/netgo/logger"
+)
+
+const (
+	DEFAULT_COOKIE_NAME      = "session"
+	DEFAULT_IDLE_TIMEOUT     = 30 * time.Minute
+	DEFAULT_ABSOLUTE_TIMEOUT = 24 * time.Hour
+	DEFAULT_GC_INTERVAL      = 10 * time.Minute
+)
+
+// prefix of the keys holding flash messages
+const flashPrefix = "_flash:"
+
+type Options struct {
+	// where the sessions are kept, defaults to a MemoryStore
+	Store      Store
+	CookieName string
+	// attributes of the session cookie, it is always HttpOnly and Secure unless Insecure is set
+	Path     string
+	Domain   string
+	Insecure bool
+	SameSite http.SameSite
+	// a session expires when it is not used for this long
+	IdleTimeout time.Duration
+	// a session expires this long after it was created however often it is used
+	AbsoluteTimeout time.Duration
+	// how often expired sessions are removed from the store, a negative value disables the collector
+	GCInterval time.Duration
+	// where the failures of the collector are logged, defaults to STDERR
+	Log *logger.Log
+}
+
+type Manager struct {
+	options Options
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// the data of a session as handed to the stores
+type Record struct {
+	ID      string
+	Values  map[string]any
+	Created time.Time
+	Expires time.Time
+}
+
+type Session struct {
+	record    *Record
+	token     string // the cookie value the session was loaded from
+	changed   bool
+	destroyed bool
+	// the request had a cookie pointing to an expired or unknown session
+	stale bool
+	// the id the session had before Rotate(), removed from the store on save
+	previousID string
+}
+
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+)
+
+// Public: creates a session manager, the garbage collector runs until Close() is called
+func NewManager(options Options) *Manager {
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+
+	if options.CookieName == "" {
+		options.CookieName = DEFAULT_COOKIE_NAME
+	}
+
+	if store, ok := options.Store.(*CookieStore); ok {
+		store.name = options.CookieName
+	}
+
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = DEFAULT_IDLE_TIMEOUT
+	}
+
+	if options.AbsoluteTimeout <= 0 {
+		options.AbsoluteTimeout = DEFAULT_ABSOLUTE_TIMEOUT
+	}
+
+	if options.GCInterval == 0 {
+		options.GCInterval = DEFAULT_GC_INTERVAL
+	}
+
+	if options.Log == nil {
+		options.Log = logger.New()
+		options.Log.Filename = logger.STDERR
+	}
+
+	m := &Manager{options: options, now: time.Now, stop: make(chan struct{})}
+
+	if options.GCInterval > 0 {
+		go m.collect()
+	}
+
+	return m
+}
+
+// stops the garbage collector
+func (m *Manager) Close() error {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+
+	return nil
+}
+
+func (m *Manager) collect() {
+	ticker := time.NewTicker(m.options.GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			if err := m.options.Store.GC(m.now()); err != nil {
+				m.options.Log.Error("session: garbage collection failed: " + err.Error())
+			}
+		}
+	}
+}
+
+// public: middleware loading the session of the request, retrieve it in handlers with FromRequest().
+// The session is saved right before the response is written, a new session that was never changed
+// does not get a cookie
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.load(r)
+
+		sw := &sessionWriter{ResponseWriter: w, save: func() {
+			if err := m.save(w, s); err != nil {
+				logger.FromRequest(r).Error("session: " + err.Error())
+			}
+		}}
+
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionKey, s)))
+		sw.saveOnce()
+	})
+}
+
+// Public: returns the session of the request, nil when the request did not go through the middleware
+func FromRequest(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey).(*Session)
+	return s
+}
+
+func (m *Manager) load(r *http.Request) *Session {
+	if cookie, err := r.Cookie(m.options.CookieName); err == nil {
+		record, err := m.options.Store.Load(cookie.Value)
+		if err != nil {
+			logger.FromRequest(r).Error("session: " + err.Error())
+		}
+
+		if record != nil && m.now().Before(record.Expires) && m.now().Before(record.Created.Add(m.options.AbsoluteTimeout)) {
+			return &Session{record: record, token: cookie.Value}
+		}
+	}
+
+	return &Session{
+		record: &Record{ID: newID(), Values: map[string]any{}, Created: m.now()},
+		stale:  hasCookie(r, m.options.CookieName),
+	}
+}
+
+func (m *Manager) save(w http.ResponseWriter, s *Session) error {
+	store := m.options.Store
+
+	if s.previousID != "" {
+		if err := store.Delete(s.previousID); err != nil {
+			return err
+		}
+	}
+
+	if s.destroyed {
+		http.SetCookie(w, m.cookie("", -1))
+		return store.Delete(s.record.ID)
+	}
+
+	// untouched new sessions are not worth a cookie, the cookie of an expired one is removed
+	if s.token == "" && !s.changed {
+		if s.stale {
+			http.SetCookie(w, m.cookie("", -1))
+		}
+		return nil
+	}
+
+	// the idle timeout starts over with every request, up to the absolute timeout
+	s.record.Expires = m.now().Add(m.options.IdleTimeout)
+	if absolute := s.record.Created.Add(m.options.AbsoluteTimeout); absolute.Before(s.record.Expires) {
+		s.record.Expires = absolute
+	}
+
+	token, err := store.Save(s.record)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, m.cookie(token, int(s.record.Expires.Sub(m.now()).Seconds())))
+
+	return nil
+}
+
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	c := cookie.New(m.options.CookieName, value, cookie.Options{
+		Path:     m.options.Path,
+		Domain:   m.options.Domain,
+		Insecure: m.options.Insecure,
+		SameSite: m.options.SameSite,
+	})
+	c.MaxAge = maxAge
+
+	return c
+}
+
+func (s *Session) ID() string {
+	return s.record.ID
+}
+
+// returns the value under the key, nil when there is none
+func (s *Session) Get(key string) any {
+	return s.record.Values[key]
+}
+
+// returns the value under the key when it is a string, empty otherwise
+func (s *Session) GetString(key string) string {
+	value, _ := s.record.Values[key].(string)
+	return value
+}
+
+// sets a value, values have to be encodable with encoding/gob. Custom types have to be
+// registered with gob.Register()
+func (s *Session) Set(key string, value any) {
+	s.record.Values[key] = value
+	s.changed = true
+}
+
+func (s *Session) Delete(key string) {
+	delete(s.record.Values, key)
+	s.changed = true
+}
+
+// removes every value but keeps the session
+func (s *Session) Clear() {
+	s.record.Values = map[string]any{}
+	s.changed = true
+}
+
+// gives the session a new id keeping its values, call it whenever the privileges change
+// (e.g. on login) so that an id known before can not be used to take the session over
+func (s *Session) Rotate() {
+	if s.previousID == "" && s.token != "" {
+		s.previousID = s.record.ID
+	}
+
+	s.record.ID = newID()
+	s.changed = true
+}
+
+// removes the session from the store and the client, e.g. on logout
+func (s *Session) Destroy() {
+	s.destroyed = true
+}
+
+// adds a message shown on the next request, like "product saved" after a redirect
+func (s *Session) Flash(kind string, message string) {
+	messages, _ := s.record.Values[flashPrefix+kind].([]string)
+	s.Set(flashPrefix+kind, append(messages, message))
+}
+
+// returns the flash messages of the kind and removes them from the session
+func (s *Session) Flashes(kind string) []string {
+	messages, ok := s.record.Values[flashPrefix+kind].([]string)
+	if ok {
+		s.Delete(flashPrefix + kind)
+	}
+
+	return messages
+}
+
+// 256 random bits, url safe so that it can be used as a cookie value and a file name
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+	return err == nil
+}
+
+// saves the session right before the headers are sent, the cookie can not be set afterwards
+type sessionWriter struct {
+	http.ResponseWriter
+	save  func()
+	saved bool
+}
+
+func (sw *sessionWriter) saveOnce() {
+	if !sw.saved {
+		sw.saved = true
+		sw.save()
+	}
+}
+
+func (sw *sessionWriter) WriteHeader(status int) {
+	sw.saveOnce()
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *sessionWriter) Write(b []byte) (int, error) {
+	sw.saveOnce()
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *sessionWriter) Flush() {
+	sw.saveOnce()
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := sw.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+
+	return nil, nil, errors.New("session: the response writer does not support hijacking")
+}
+
+// lets http.ResponseController reach the original writer
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
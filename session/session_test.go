@@ -0,0 +1,237 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waponix/netgo/cookie"
+	"github.com/waponix/netgo/logger"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestManager(store Store) (*Manager, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	m := NewManager(Options{
+		Store:           store,
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 2 * time.Hour,
+		GCInterval:      -1,
+	})
+	m.now = clock.Now
+
+	return m, clock
+}
+
+// runs the handler through the middleware with the cookie, returns the response
+func request(m *Manager, cookie *http.Cookie, handler func(*Session, http.ResponseWriter)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(FromRequest(r), w)
+	})).ServeHTTP(rec, req)
+
+	return rec
+}
+
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == DEFAULT_COOKIE_NAME {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestSessionShouldBeKeptBetweenRequests(t *testing.T) {
+	codec, _ := cookie.Signed(cookie.Options{}, []byte("0123456789abcdef0123456789abcdef"))
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"cookie": NewCookieStore(codec),
+	}
+	if fileStore, err := NewFileStore(t.TempDir()); err == nil {
+		stores["file"] = fileStore
+	}
+
+	for name, store := range stores {
+		m, _ := newTestManager(store)
+
+		rec := request(m, nil, func(s *Session, w http.ResponseWriter) {
+			s.Set("userId", "42")
+			w.Write([]byte("ok"))
+		})
+
+		cookie := sessionCookie(rec)
+		if cookie == nil || !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge != 1800 {
+			t.Fatalf(`%s: session cookie = %v, want a secure HttpOnly cookie living 1800s`, name, cookie)
+		}
+
+		var userID string
+		request(m, cookie, func(s *Session, w http.ResponseWriter) {
+			userID = s.GetString("userId")
+		})
+
+		if userID != "42" {
+			t.Fatalf(`%s: userId on the next request = %q, want "42"`, name, userID)
+		}
+	}
+}
+
+func TestUntouchedSessionShouldNotGetACookie(t *testing.T) {
+	m, _ := newTestManager(NewMemoryStore())
+
+	if cookie := sessionCookie(request(m, nil, func(s *Session, w http.ResponseWriter) {})); cookie != nil {
+		t.Fatalf(`session cookie = %v, want none`, cookie)
+	}
+
+	cookie := sessionCookie(request(m, &http.Cookie{Name: DEFAULT_COOKIE_NAME, Value: "unknown"}, func(s *Session, w http.ResponseWriter) {}))
+	if cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf(`session cookie of an unknown session = %v, want it removed`, cookie)
+	}
+}
+
+func TestSessionShouldExpire(t *testing.T) {
+	store := NewMemoryStore()
+	m, clock := newTestManager(store)
+
+	cookie := sessionCookie(request(m, nil, func(s *Session, w http.ResponseWriter) {
+		s.Set("userId", "42")
+	}))
+
+	read := func() string {
+		var userID string
+		rec := request(m, cookie, func(s *Session, w http.ResponseWriter) {
+			userID = s.GetString("userId")
+		})
+		if next := sessionCookie(rec); next != nil && next.MaxAge > 0 {
+			cookie = next
+		}
+		return userID
+	}
+
+	// every request restarts the idle timeout
+	for i := 0; i < 3; i++ {
+		clock.now = clock.now.Add(25 * time.Minute)
+		if userID := read(); userID != "42" {
+			t.Fatalf(`userId after %d idle periods = %q, want "42"`, i+1, userID)
+		}
+	}
+
+	// but not the absolute one
+	clock.now = clock.now.Add(25 * time.Minute)
+	read()
+	clock.now = clock.now.Add(25 * time.Minute)
+	if userID := read(); userID != "" {
+		t.Fatalf(`userId past the absolute timeout = %q, want ""`, userID)
+	}
+
+	store.GC(clock.now)
+	if store.Len() != 0 {
+		t.Fatalf(`store.Len() after GC = %d, want 0`, store.Len())
+	}
+}
+
+func TestRotateShouldReplaceTheSessionID(t *testing.T) {
+	store := NewMemoryStore()
+	m, _ := newTestManager(store)
+
+	var before string
+	cookie := sessionCookie(request(m, nil, func(s *Session, w http.ResponseWriter) {
+		s.Set("cart", "1")
+		before = s.ID()
+	}))
+
+	var after string
+	rotated := sessionCookie(request(m, cookie, func(s *Session, w http.ResponseWriter) {
+		s.Rotate()
+		s.Set("userId", "42")
+		after = s.ID()
+	}))
+
+	if after == before || rotated.Value == cookie.Value || store.Len() != 1 {
+		t.Fatalf(`ids %q -> %q with %d sessions stored, want a new id replacing the old one`, before, after, store.Len())
+	}
+
+	var cart string
+	request(m, rotated, func(s *Session, w http.ResponseWriter) {
+		cart = s.GetString("cart")
+	})
+	if cart != "1" {
+		t.Fatalf(`cart after Rotate() = %q, want "1"`, cart)
+	}
+
+	if rec := request(m, cookie, func(s *Session, w http.ResponseWriter) { cart = s.GetString("cart") }); cart != "" || sessionCookie(rec).MaxAge >= 0 {
+		t.Fatalf(`cart with the old cookie = %q, want ""`, cart)
+	}
+}
+
+func TestDestroyShouldRemoveTheSession(t *testing.T) {
+	store := NewMemoryStore()
+	m, _ := newTestManager(store)
+
+	cookie := sessionCookie(request(m, nil, func(s *Session, w http.ResponseWriter) { s.Set("userId", "42") }))
+	removed := sessionCookie(request(m, cookie, func(s *Session, w http.ResponseWriter) { s.Destroy() }))
+
+	if removed == nil || removed.MaxAge >= 0 || store.Len() != 0 {
+		t.Fatalf(`cookie after Destroy() = %v with %d sessions stored, want it removed`, removed, store.Len())
+	}
+}
+
+func TestFlashesShouldBeReadOnce(t *testing.T) {
+	m, _ := newTestManager(NewMemoryStore())
+
+	cookie := sessionCookie(request(m, nil, func(s *Session, w http.ResponseWriter) {
+		s.Flash("success", "product saved")
+		s.Flash("success", "stock updated")
+	}))
+
+	var flashes []string
+	cookie = sessionCookie(request(m, cookie, func(s *Session, w http.ResponseWriter) { flashes = s.Flashes("success") }))
+	if len(flashes) != 2 || flashes[0] != "product saved" {
+		t.Fatalf(`Flashes("success") = %q, want both messages`, flashes)
+	}
+
+	request(m, cookie, func(s *Session, w http.ResponseWriter) { flashes = s.Flashes("success") })
+	if len(flashes) != 0 {
+		t.Fatalf(`Flashes("success") on the next request = %q, want none`, flashes)
+	}
+}
+
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) GC(time.Time) error {
+	return errors.New("disk full")
+}
+
+func TestGarbageCollectionFailuresShouldBeLogged(t *testing.T) {
+	log := logger.New()
+	log.Filename = filepath.Join(t.TempDir(), "session.log")
+
+	m := NewManager(Options{Store: failingStore{NewMemoryStore()}, GCInterval: time.Millisecond, Log: log})
+	time.Sleep(20 * time.Millisecond)
+	m.Close()
+
+	content, _ := os.ReadFile(log.Filename)
+	if !strings.Contains(string(content), "session: garbage collection failed: disk full") {
+		t.Fatalf(`log = %q, want the garbage collection failure`, content)
+	}
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidID = errors.New("session: invalid session id")
+
+// keeps the sessions, implement it to keep them in Redis or an SQL database
+type Store interface {
+	// returns the record the token from the cookie points to, nil when there is none
+	Load(token string) (*Record, error)
+	// saves the record and returns the token to put in the cookie
+	Save(record *Record) (string, error)
+	Delete(id string) error
+	// removes the records that expired before now, called periodically by the manager
+	GC(now time.Time) error
+}
+
+// keeps the sessions in memory, they are lost on restart and not shared between processes
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	// records are kept encoded so that concurrent requests never share the values
+	data    []byte
+	expires time.Time
+}
+
+// Public: creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (s *MemoryStore) Load(token string) (*Record, error) {
+	s.mu.RLock()
+	stored, ok := s.records[token]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	return decodeRecord(stored.data)
+}
+
+func (s *MemoryStore) Save(record *Record) (string, error) {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.records[record.ID] = memoryRecord{data: data, expires: record.Expires}
+	s.mu.Unlock()
+
+	return record.ID, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.records, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryStore) GC(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stored := range s.records {
+		if !now.Before(stored.expires) {
+			delete(s.records, id)
+		}
+	}
+
+	return nil
+}
+
+// returns the number of sessions kept
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.records)
+}
+
+func encodeRecord(record *Record) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeRecord(data []byte) (*Record, error) {
+	record := &Record{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(record); err != nil {
+		return nil, err
+	}
+
+	if record.Values == nil {
+		record.Values = map[string]any{}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waponix/netgo/cookie"
+)
+
+func TestCookieStoreShouldRejectTamperedTokens(t *testing.T) {
+	codec, _ := cookie.Encrypted(cookie.Options{}, []byte("0123456789abcdef0123456789abcdef"))
+	store := NewCookieStore(codec)
+
+	token, err := store.Save(&Record{ID: "id", Values: map[string]any{"role": "user"}})
+	if err != nil {
+		t.Fatalf(`Save() error = %v, want nil`, err)
+	}
+
+	for _, forged := range []string{token[:len(token)-2] + "xx", "x" + token, "garbage", ""} {
+		if record, _ := store.Load(forged); record != nil {
+			t.Fatalf(`Load(%q) = %v, want nil`, forged, record)
+		}
+	}
+
+	otherCodec, _ := cookie.Encrypted(cookie.Options{}, []byte("another key of 32 bytes........."))
+	other := NewCookieStore(otherCodec)
+	if record, _ := other.Load(token); record != nil {
+		t.Fatalf(`Load() with another key = %v, want nil`, record)
+	}
+
+	if _, err := store.Save(&Record{ID: "id", Values: map[string]any{"big": string(make([]byte, cookie.MAX_SIZE))}}); err != cookie.ErrTooLarge {
+		t.Fatalf(`Save() of a large session error = %v, want cookie.ErrTooLarge`, err)
+	}
+}
+
+func TestCookieStoreShouldUseTheCookieNameOfTheManager(t *testing.T) {
+	codec, _ := cookie.Signed(cookie.Options{}, []byte("0123456789abcdef0123456789abcdef"))
+	m := NewManager(Options{Store: NewCookieStore(codec), CookieName: "sid", GCInterval: -1})
+
+	token, _ := m.options.Store.Save(&Record{ID: "id", Values: map[string]any{}})
+	if _, err := codec.Open("sid", token); err != nil {
+		t.Fatalf(`codec.Open("sid") error = %v, want nil`, err)
+	}
+
+	if _, err := codec.Open(DEFAULT_COOKIE_NAME, token); err == nil {
+		t.Fatalf(`codec.Open(%q) error = nil, want the value to be bound to "sid"`, DEFAULT_COOKIE_NAME)
+	}
+}
+
+func TestFileStoreShouldCollectExpiredSessions(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	store.Save(&Record{ID: "expired", Expires: now.Add(-time.Minute)})
+	store.Save(&Record{ID: "alive", Expires: now.Add(time.Minute)})
+	os.WriteFile(filepath.Join(dir, "broken"+FILE_EXTENSION), []byte("not gob"), 0o600)
+
+	// a file that can not be decoded is reported, it is not known to be expired
+	if err := store.GC(now); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf(`GC() error = %v, want an error about broken`, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != "alive"+FILE_EXTENSION || entries[1].Name() != "broken"+FILE_EXTENSION {
+		t.Fatalf(`files after GC() = %v, want alive and broken`, entries)
+	}
+
+	for _, id := range []string{"../escape", "a/b", ""} {
+		if _, err := store.Save(&Record{ID: id}); err != ErrInvalidID {
+			t.Fatalf(`Save() with id %q error = %v, want ErrInvalidID`, id, err)
+		}
+	}
+}
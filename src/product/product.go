@@ -1,14 +1,18 @@
 package product
 
 import (
-	"fmt"
 	"net/http"
 
-	"github.com/gorilla/mux"
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+	"github.com/waponix/netgo/view"
 )
 
 func GetProductHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	fmt.Fprintf(w, "<h1>"+vars["productId"]+"</h1>")
-	w.WriteHeader(http.StatusOK)
+	productId := router.Param(r, "productId")
+	logger.FromRequest(r).Info("showing product " + productId)
+
+	view.Instance().HTML(w, r, http.StatusOK, "product/show", map[string]any{
+		"ProductId": productId,
+	})
 }
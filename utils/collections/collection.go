@@ -0,0 +1,268 @@
+package collections
+
+import (
+	"iter"
+	"sort"
+	"sync"
+)
+
+// a lazy pipeline over a sequence, nothing is evaluated until the collection is consumed
+// through ToSlice(), Each(), Count(), First() or by ranging over Seq()
+type Collection[T any] struct {
+	seq iter.Seq[T]
+}
+
+// starts a pipeline over the items of a slice
+func From[T any](items []T) *Collection[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// starts a pipeline over any iterator, e.g. maps.Keys()
+func FromSeq[T any](seq iter.Seq[T]) *Collection[T] {
+	return &Collection[T]{seq: seq}
+}
+
+// returns the underlying iterator
+func (c *Collection[T]) Seq() iter.Seq[T] {
+	return c.seq
+}
+
+// keeps the items for which fn returns true
+func (c *Collection[T]) Filter(fn func(T) bool) *Collection[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for item := range c.seq {
+			if fn(item) && !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// transforms every item, use the package level Map() to change the item type
+func (c *Collection[T]) Map(fn func(T) T) *Collection[T] {
+	return Map(c, fn)
+}
+
+// sorts the items with the less function, the sort is stable. The whole input is
+// read once the first item is requested
+func (c *Collection[T]) SortBy(less func(a T, b T) bool) *Collection[T] {
+	return FromSeq(func(yield func(T) bool) {
+		items := c.ToSlice()
+		sort.SliceStable(items, func(i, j int) bool {
+			return less(items[i], items[j])
+		})
+
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// stops the pipeline after n items, the rest of the input is never evaluated
+func (c *Collection[T]) Take(n int) *Collection[T] {
+	return FromSeq(func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+
+		taken := 0
+		for item := range c.seq {
+			if !yield(item) {
+				return
+			}
+			taken++
+			if taken >= n {
+				return
+			}
+		}
+	})
+}
+
+// skips the first n items
+func (c *Collection[T]) Skip(n int) *Collection[T] {
+	return FromSeq(func(yield func(T) bool) {
+		skipped := 0
+		for item := range c.seq {
+			if skipped < n {
+				skipped++
+				continue
+			}
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// runs the pipeline and collects the items
+func (c *Collection[T]) ToSlice() []T {
+	var items []T
+	for item := range c.seq {
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// runs the pipeline calling fn for every item
+func (c *Collection[T]) Each(fn func(T)) {
+	for item := range c.seq {
+		fn(item)
+	}
+}
+
+// runs the pipeline and counts the items
+func (c *Collection[T]) Count() int {
+	count := 0
+	for range c.seq {
+		count++
+	}
+
+	return count
+}
+
+// returns the first item, the second value is false when the collection is empty
+func (c *Collection[T]) First() (T, bool) {
+	for item := range c.seq {
+		return item, true
+	}
+
+	var zero T
+	return zero, false
+}
+
+// transforms every item into another type
+func Map[T any, U any](c *Collection[T], fn func(T) U) *Collection[U] {
+	return FromSeq(func(yield func(U) bool) {
+		for item := range c.seq {
+			if !yield(fn(item)) {
+				return
+			}
+		}
+	})
+}
+
+// runs the pipeline and folds the items into a single value
+func Reduce[T any, U any](c *Collection[T], initial U, fn func(U, T) U) U {
+	result := initial
+	for item := range c.seq {
+		result = fn(result, item)
+	}
+
+	return result
+}
+
+// like Map() but fn runs on the given number of goroutines, meant for CPU bound work.
+// The input order is kept and the workers stop as soon as the consumer stops. At most workers
+// items are taken from the input before they are consumed, and a panic of fn is raised again
+// on the goroutine consuming the collection
+func ParallelMap[T any, U any](c *Collection[T], workers int, fn func(T) U) *Collection[U] {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	type job struct {
+		index int
+		item  T
+	}
+
+	type result struct {
+		index int
+		value U
+		// what fn panicked with
+		failed   bool
+		panicked any
+	}
+
+	apply := func(j job) (r result) {
+		r.index = j.index
+		defer func() {
+			if v := recover(); v != nil {
+				r.failed, r.panicked = true, v
+			}
+		}()
+
+		r.value = fn(j.item)
+		return r
+	}
+
+	return FromSeq(func(yield func(U) bool) {
+		jobs := make(chan job)
+		results := make(chan result, workers)
+		// one per item taken from the input and not consumed yet, so that a slow item does not
+		// let the workers buffer the rest of the input
+		slots := make(chan struct{}, workers)
+		done := make(chan struct{})
+		defer close(done)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					select {
+					case results <- apply(j):
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			defer close(jobs)
+			index := 0
+			for item := range c.seq {
+				select {
+				case slots <- struct{}{}:
+				case <-done:
+					return
+				}
+
+				select {
+				case jobs <- job{index: index, item: item}:
+					index++
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		// results come back in any order, hold them until it is their turn
+		pending := make(map[int]U)
+		next := 0
+		for r := range results {
+			if r.failed {
+				panic(r.panicked)
+			}
+
+			pending[r.index] = r.value
+			for {
+				value, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				<-slots
+				if !yield(value) {
+					return
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package collections
+
+import (
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollectionPipeline(t *testing.T) {
+	products := []product{{5, "a"}, {3, "b"}, {8, "a"}, {1, "a"}, {9, "a"}}
+
+	ids := Map(
+		From(products).
+			Filter(func(p product) bool { return p.Category == "a" }).
+			SortBy(func(a, b product) bool { return a.Id < b.Id }).
+			Skip(1).
+			Take(2),
+		func(p product) int { return p.Id },
+	).ToSlice()
+
+	if !reflect.DeepEqual(ids, []int{5, 8}) {
+		t.Fatalf(`pipeline = %v, want [5 8]`, ids)
+	}
+}
+
+func TestCollectionIsLazy(t *testing.T) {
+	evaluated := 0
+	c := From([]int{1, 2, 3, 4, 5}).Map(func(i int) int {
+		evaluated++
+		return i * 2
+	})
+
+	if evaluated != 0 {
+		t.Fatalf(`Map() evaluated %d items before the collection was consumed, want 0`, evaluated)
+	}
+
+	first, ok := c.Take(2).First()
+	if first != 2 || !ok || evaluated != 1 {
+		t.Fatalf(`Take(2).First() = %d, %v with %d evaluations, want 2, true with 1 evaluation`, first, ok, evaluated)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := Reduce(From([]int{1, 2, 3}), 0, func(total int, i int) int { return total + i })
+	if sum != 6 {
+		t.Fatalf(`Reduce() = %d, want 6`, sum)
+	}
+}
+
+func TestParallelMapKeepsOrder(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	result := ParallelMap(From(items), 4, strconv.Itoa).ToSlice()
+
+	for i, value := range result {
+		if value != strconv.Itoa(i) {
+			t.Fatalf(`ParallelMap()[%d] = %q, want %q`, i, value, strconv.Itoa(i))
+		}
+	}
+
+	if len(result) != 100 {
+		t.Fatalf(`len(ParallelMap()) = %d, want 100`, len(result))
+	}
+
+	// stopping early should not block
+	if taken := ParallelMap(From(items), 4, strconv.Itoa).Take(3).ToSlice(); !reflect.DeepEqual(taken, []string{"0", "1", "2"}) {
+		t.Fatalf(`ParallelMap().Take(3) = %v, want [0 1 2]`, taken)
+	}
+}
+
+func TestParallelMapShouldNotRunAheadOfASlowItem(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	var started atomic.Int32
+	release := make(chan struct{})
+	slow := func(i int) int {
+		started.Add(1)
+		if i == 0 {
+			<-release
+		}
+		return i
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if n := started.Load(); n > 2 {
+			t.Errorf(`items started while the first one is slow = %d, want at most 2`, n)
+		}
+		close(release)
+	}()
+
+	if result := ParallelMap(From(items), 2, slow).ToSlice(); len(result) != 100 {
+		t.Fatalf(`len(ParallelMap()) = %d, want 100`, len(result))
+	}
+}
+
+func TestParallelMapShouldRaisePanicsOnTheConsumer(t *testing.T) {
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Fatalf(`recover() = %v, want "boom"`, v)
+		}
+	}()
+
+	ParallelMap(From([]int{1, 2, 3}), 2, func(i int) int {
+		if i == 2 {
+			panic("boom")
+		}
+		return i
+	}).ToSlice()
+
+	t.Fatalf(`ParallelMap() did not panic`)
+}
+
+type product struct {
+	Id       int
+	Category string
+}
@@ -0,0 +1,96 @@
+package collections
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestOrderedMapKeepsInsertionOrder(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	m.Set("c", 1).Set("a", 2).Set("b", 3).Set("a", 4).Delete("c")
+
+	if keys := m.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf(`m.Keys() = %v, want [a b]`, keys)
+	}
+
+	if values := m.Values(); !reflect.DeepEqual(values, []int{4, 3}) {
+		t.Fatalf(`m.Values() = %v, want [4 3]`, values)
+	}
+
+	if _, ok := m.Get("c"); ok || m.Len() != 2 {
+		t.Fatalf(`deleted key "c" is still present`)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a := NewSet("GET", "POST", "GET")
+	b := NewSet("POST", "DELETE")
+
+	if a.Len() != 2 || !a.Has("POST") || a.Has("PUT") {
+		t.Fatalf(`NewSet("GET", "POST", "GET") = %v, want [GET POST]`, a.Values())
+	}
+
+	if union := a.Union(b).Values(); !reflect.DeepEqual(union, []string{"GET", "POST", "DELETE"}) {
+		t.Fatalf(`a.Union(b) = %v, want [GET POST DELETE]`, union)
+	}
+
+	if intersect := a.Intersect(b).Values(); !reflect.DeepEqual(intersect, []string{"POST"}) {
+		t.Fatalf(`a.Intersect(b) = %v, want [POST]`, intersect)
+	}
+
+	if diff := a.Diff(b).Values(); !reflect.DeepEqual(diff, []string{"GET"}) {
+		t.Fatalf(`a.Diff(b) = %v, want [GET]`, diff)
+	}
+
+	var empty *Set[string]
+	if empty.Has("GET") || empty.Len() != 0 {
+		t.Fatalf(`a nil set should behave as an empty set`)
+	}
+}
+
+func TestSyncOrderedMapConcurrentAccess(t *testing.T) {
+	m := NewSyncOrderedMap[int, int]()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.GetOrSet(i%10, i)
+			m.Has(i)
+		}(i)
+	}
+	wg.Wait()
+
+	if m.Len() != 10 {
+		t.Fatalf(`m.Len() = %d, want 10`, m.Len())
+	}
+}
+
+func TestZeroValuesShouldBeUsable(t *testing.T) {
+	var m OrderedMap[string, int]
+	if m.Len() != 0 || m.Has("a") {
+		t.Fatalf(`zero OrderedMap = %v, want empty`, m.Keys())
+	}
+
+	m.Set("a", 1).Delete("b")
+	if value, ok := m.Get("a"); !ok || value != 1 {
+		t.Fatalf(`m.Get("a") = %d, %v, want 1, true`, value, ok)
+	}
+
+	var s Set[string]
+	s.Remove("a")
+	if s.Add("a", "b", "a").Len() != 2 || !s.Has("b") {
+		t.Fatalf(`zero Set after Add = %v, want [a b]`, s.Values())
+	}
+
+	var sm SyncOrderedMap[string, int]
+	if _, ok := sm.Get("a"); ok {
+		t.Fatalf(`zero SyncOrderedMap.Get("a") ok = true, want false`)
+	}
+
+	if value, ok := sm.GetOrSet("a", 1); ok || value != 1 || sm.Len() != 1 {
+		t.Fatalf(`sm.GetOrSet("a", 1) = %d, %v, want 1, false`, value, ok)
+	}
+}
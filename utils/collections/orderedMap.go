@@ -0,0 +1,211 @@
+package collections
+
+import (
+	"sync"
+)
+
+// a map that remembers the order in which its keys were first set. The zero value is an empty map
+// ready to use, a nil map is treated as an empty map by the read only methods
+type OrderedMap[K comparable, V any] struct {
+	keys   []K
+	values map[K]V
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		values: make(map[K]V),
+	}
+}
+
+// sets the value of the key, an existing key keeps its position
+func (m *OrderedMap[K, V]) Set(key K, value V) *OrderedMap[K, V] {
+	if m.values == nil {
+		m.values = make(map[K]V)
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+
+	return m
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if m == nil {
+		var zero V
+		return zero, false
+	}
+
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	if m == nil {
+		return false
+	}
+
+	_, ok := m.values[key]
+	return ok
+}
+
+// removes the key, does nothing when the key is not set
+func (m *OrderedMap[K, V]) Delete(key K) *OrderedMap[K, V] {
+	if !m.Has(key) {
+		return m
+	}
+
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+
+	return m
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.keys)
+}
+
+// returns a copy of the keys in insertion order
+func (m *OrderedMap[K, V]) Keys() []K {
+	if m == nil {
+		return nil
+	}
+
+	keys := make([]K, len(m.keys))
+	copy(keys, m.keys)
+
+	return keys
+}
+
+// returns the values in insertion order
+func (m *OrderedMap[K, V]) Values() []V {
+	if m == nil {
+		return nil
+	}
+
+	values := make([]V, len(m.keys))
+	for i, k := range m.keys {
+		values[i] = m.values[k]
+	}
+
+	return values
+}
+
+// calls fn for every entry in insertion order, stops when fn returns false
+func (m *OrderedMap[K, V]) Each(fn func(K, V) bool) {
+	if m == nil {
+		return
+	}
+
+	for _, k := range m.keys {
+		if !fn(k, m.values[k]) {
+			return
+		}
+	}
+}
+
+// an OrderedMap that can be shared between goroutines, the zero value is ready to use
+type SyncOrderedMap[K comparable, V any] struct {
+	mutex sync.RWMutex
+	m     *OrderedMap[K, V]
+}
+
+func NewSyncOrderedMap[K comparable, V any]() *SyncOrderedMap[K, V] {
+	return &SyncOrderedMap[K, V]{
+		m: NewOrderedMap[K, V](),
+	}
+}
+
+// returns the map, creating it on the first write. Requires the write lock
+func (s *SyncOrderedMap[K, V]) writable() *OrderedMap[K, V] {
+	if s.m == nil {
+		s.m = NewOrderedMap[K, V]()
+	}
+
+	return s.m
+}
+
+func (s *SyncOrderedMap[K, V]) Set(key K, value V) *SyncOrderedMap[K, V] {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.writable().Set(key, value)
+
+	return s
+}
+
+func (s *SyncOrderedMap[K, V]) Get(key K) (V, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.m.Get(key)
+}
+
+// returns the value of the key, setting it to value first when the key is not set yet
+func (s *SyncOrderedMap[K, V]) GetOrSet(key K, value V) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, ok := s.m.Get(key); ok {
+		return existing, true
+	}
+	s.writable().Set(key, value)
+
+	return value, false
+}
+
+func (s *SyncOrderedMap[K, V]) Has(key K) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.m.Has(key)
+}
+
+func (s *SyncOrderedMap[K, V]) Delete(key K) *SyncOrderedMap[K, V] {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.m.Delete(key)
+
+	return s
+}
+
+func (s *SyncOrderedMap[K, V]) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.m.Len()
+}
+
+func (s *SyncOrderedMap[K, V]) Keys() []K {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.m.Keys()
+}
+
+func (s *SyncOrderedMap[K, V]) Values() []V {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.m.Values()
+}
+
+// calls fn for every entry in insertion order while holding the read lock,
+// fn must not modify the map
+func (s *SyncOrderedMap[K, V]) Each(fn func(K, V) bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	s.m.Each(fn)
+}
@@ -0,0 +1,91 @@
+// generic collection types that are not covered by the builtin slice and map
+package collections
+
+// a set of unique items, iteration follows the order in which items were added. The zero value is an
+// empty set ready to use
+type Set[T comparable] struct {
+	items *OrderedMap[T, struct{}]
+}
+
+func NewSet[T comparable](items ...T) *Set[T] {
+	s := &Set[T]{
+		items: NewOrderedMap[T, struct{}](),
+	}
+
+	return s.Add(items...)
+}
+
+func (s *Set[T]) Add(items ...T) *Set[T] {
+	if s.items == nil {
+		s.items = NewOrderedMap[T, struct{}]()
+	}
+
+	for _, item := range items {
+		s.items.Set(item, struct{}{})
+	}
+
+	return s
+}
+
+func (s *Set[T]) Remove(items ...T) *Set[T] {
+	for _, item := range items {
+		s.items.Delete(item)
+	}
+
+	return s
+}
+
+// a nil set is treated as an empty set by the read only methods
+func (s *Set[T]) Has(item T) bool {
+	if s == nil {
+		return false
+	}
+
+	return s.items.Has(item)
+}
+
+func (s *Set[T]) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	return s.items.Len()
+}
+
+// returns the items in insertion order
+func (s *Set[T]) Values() []T {
+	if s == nil {
+		return nil
+	}
+
+	return s.items.Keys()
+}
+
+// returns a new set holding the items of both sets
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	return NewSet(s.Values()...).Add(other.Values()...)
+}
+
+// returns a new set holding the items that are in both sets
+func (s *Set[T]) Intersect(other *Set[T]) *Set[T] {
+	result := NewSet[T]()
+	for _, item := range s.Values() {
+		if other.Has(item) {
+			result.Add(item)
+		}
+	}
+
+	return result
+}
+
+// returns a new set holding the items that are not in the other set
+func (s *Set[T]) Diff(other *Set[T]) *Set[T] {
+	result := NewSet[T]()
+	for _, item := range s.Values() {
+		if !other.Has(item) {
+			result.Add(item)
+		}
+	}
+
+	return result
+}
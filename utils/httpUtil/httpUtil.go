@@ -0,0 +1,33 @@
+// all related utilities for handling http requests
+package httpUtil
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// returns the IP of the client. Behind a trusted proxy it is read from X-Forwarded-For, or from
+// X-Real-IP when there is none. Proxies append the address they got the request from, so only the
+// right most entry of X-Forwarded-For comes from the proxy, the ones before it are sent by the client
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
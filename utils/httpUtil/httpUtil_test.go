@@ -0,0 +1,39 @@
+package httpUtil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPShouldOnlyTrustTheProxy(t *testing.T) {
+	cases := []struct {
+		forwarded  []string
+		realIP     string
+		trustProxy bool
+		want       string
+	}{
+		{nil, "", false, "192.0.2.1"},
+		{[]string{"203.0.113.7"}, "", false, "192.0.2.1"},
+		{[]string{"203.0.113.7"}, "", true, "203.0.113.7"},
+		// the client sent the left most entries itself
+		{[]string{"1.1.1.1, 2.2.2.2, 203.0.113.7"}, "", true, "203.0.113.7"},
+		{[]string{"1.1.1.1", "203.0.113.7"}, "", true, "203.0.113.7"},
+		{nil, "203.0.113.8", true, "203.0.113.8"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for _, value := range c.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+
+		if got := ClientIP(req, c.trustProxy); got != c.want {
+			t.Fatalf(`ClientIP(%v, %q, %v) = %q, want %q`, c.forwarded, c.realIP, c.trustProxy, got, c.want)
+		}
+	}
+}
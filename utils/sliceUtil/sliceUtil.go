@@ -2,117 +2,161 @@
 package sliceUtil
 
 import (
-	"fmt"
-	"log"
-	"runtime"
+	"errors"
 )
 
-type slice struct {
-	Items []interface{}
+var ErrInvalidSize = errors.New("sliceUtil: size must be greater than zero")
+
+type slice[T comparable] struct {
+	Items []T
+}
+
+// looks through items for that matches the needle
+func (s *slice[T]) InItems(needle T) bool {
+	return Contains(s.Items, needle)
 }
 
-// (Handles string type) looks through items for that matches the needle
-func (s *slice) InItems(needle interface{}) bool {
-	if len(s.Items) <= 0 {
-		return false
+// call this first to be able to chain the helpers on any comparable slice type
+func Use[T comparable](items []T) *slice[T] {
+	copied := make([]T, len(items))
+	copy(copied, items)
+
+	return &slice[T]{
+		Items: copied,
 	}
+}
 
-	for _, item := range s.Items {
-		if needle == item {
-			return true
+// reports whether the needle is one of the items
+func Contains[T comparable](items []T, needle T) bool {
+	return IndexOf(items, needle) >= 0
+}
+
+// returns the index of the first item equal to the needle, -1 when there is none
+func IndexOf[T comparable](items []T, needle T) int {
+	for i, item := range items {
+		if item == needle {
+			return i
 		}
 	}
 
-	return false
+	return -1
 }
 
-func getCallerInfo(level int) (string, int) {
-	// Get information about the caller at depth 1 (the immediate caller)
-	_, file, line, ok := runtime.Caller(level)
-	if ok {
-		return file, line
-	} else {
-		// this will most likely never be reached
-		return "Unknown", 0
+// returns a new slice holding the result of fn for every item
+func Map[T any, U any](items []T, fn func(T) U) []U {
+	result := make([]U, len(items))
+	for i, item := range items {
+		result[i] = fn(item)
 	}
+
+	return result
 }
 
-// call this first to be able to handle any slice type
-func Use(o ...interface{}) *slice {
-	var anonSlice []interface{}
-
-	for {
-		var ok bool
-
-		// handle string slice
-		var strS []string
-		strS, ok = o[0].([]string)
-		if ok {
-			anonSlice = make([]interface{}, len(strS))
-			for k, v := range strS {
-				anonSlice[k] = v
-			}
-			break
+// returns the items for which fn returns true
+func Filter[T any](items []T, fn func(T) bool) []T {
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		if fn(item) {
+			result = append(result, item)
 		}
+	}
 
-		// handle int slice
-		var intS []int
-		intS, ok = o[0].([]int)
-		if ok {
-			anonSlice = make([]interface{}, len(intS))
-			for k, v := range intS {
-				anonSlice[k] = v
-			}
-			break
-		}
+	return result
+}
 
-		// handle float32 slice
-		flt32S, ok := o[0].([]float32)
-		if ok {
-			anonSlice = make([]interface{}, len(flt32S))
-			for k, v := range flt32S {
-				anonSlice[k] = v
-			}
-			break
-		}
+// folds the items into a single value starting from initial
+func Reduce[T any, U any](items []T, initial U, fn func(U, T) U) U {
+	result := initial
+	for _, item := range items {
+		result = fn(result, item)
+	}
 
-		// handle float64 slice
-		flt64S, ok := o[0].([]float64)
-		if ok {
-			anonSlice = make([]interface{}, len(flt64S))
-			for k, v := range flt64S {
-				anonSlice[k] = v
-			}
-			break
-		}
+	return result
+}
 
-		// handle bool slice
-		boolS, ok := o[0].([]bool)
-		if ok {
-			anonSlice = make([]interface{}, len(boolS))
-			for k, v := range boolS {
-				anonSlice[k] = v
-			}
-			break
+// returns the items without duplicates, keeping the first occurrence
+func Unique[T comparable](items []T) []T {
+	seen := make(map[T]struct{}, len(items))
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		if _, ok := seen[item]; ok {
+			continue
 		}
+		seen[item] = struct{}{}
+		result = append(result, item)
+	}
+
+	return result
+}
 
-		// handle interface slice
-		mixedS, ok := o[0].([]interface{})
-		if ok {
-			anonSlice = make([]interface{}, len(mixedS))
-			copy(anonSlice, mixedS)
-			break
+// splits the items into slices of at most size items
+func Chunk[T any](items []T, size int) ([][]T, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	chunks := make([][]T, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		end := start + size
+		if end > len(items) {
+			end = len(items)
 		}
+		chunks = append(chunks, items[start:end:end])
+	}
+
+	return chunks, nil
+}
+
+// groups the items by the key returned by fn, the order of the items is kept within a group
+func GroupBy[T any, K comparable](items []T, fn func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, item := range items {
+		key := fn(item)
+		groups[key] = append(groups[key], item)
+	}
+
+	return groups
+}
 
-		file, line := getCallerInfo(2)
-		log.Fatal(fmt.Sprintf("Passed unsupported type to slice.Use() called in %s on line %d", file, line))
-		break
+// splits the items into the ones for which fn returns true and the rest
+func Partition[T any](items []T, fn func(T) bool) ([]T, []T) {
+	var matched, rest []T
+	for _, item := range items {
+		if fn(item) {
+			matched = append(matched, item)
+		} else {
+			rest = append(rest, item)
+		}
 	}
 
-	interfaceSlice := make([]interface{}, len(anonSlice))
-	copy(interfaceSlice, anonSlice)
+	return matched, rest
+}
+
+// returns the items of a that are not in b
+func Diff[T comparable](a []T, b []T) []T {
+	exclude := toSet(b)
+
+	return Filter(a, func(item T) bool {
+		_, ok := exclude[item]
+		return !ok
+	})
+}
 
-	return &slice{
-		Items: interfaceSlice,
+// returns the unique items that are both in a and b, in the order of a
+func Intersect[T comparable](a []T, b []T) []T {
+	include := toSet(b)
+
+	return Unique(Filter(a, func(item T) bool {
+		_, ok := include[item]
+		return ok
+	}))
+}
+
+func toSet[T comparable](items []T) map[T]struct{} {
+	set := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		set[item] = struct{}{}
 	}
+
+	return set
 }
@@ -0,0 +1,97 @@
+package sliceUtil
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type product struct {
+	Id       int
+	Category string
+}
+
+func TestUseInItems(t *testing.T) {
+	if !Use([]string{"GET", "POST"}).InItems("POST") {
+		t.Fatalf(`Use([]string{"GET", "POST"}).InItems("POST") = false, want true`)
+	}
+
+	// any comparable type is supported, including structs
+	if Use([]product{{1, "a"}}).InItems(product{2, "a"}) {
+		t.Fatalf(`Use([]product{{1, "a"}}).InItems(product{2, "a"}) = true, want false`)
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	if i := IndexOf([]float64{1.5, 2.5, 2.5}, 2.5); i != 1 {
+		t.Fatalf(`IndexOf() = %d, want 1`, i)
+	}
+
+	if i := IndexOf([]bool{}, true); i != -1 {
+		t.Fatalf(`IndexOf() on empty slice = %d, want -1`, i)
+	}
+}
+
+func TestMapFilterReduce(t *testing.T) {
+	items := []int{1, 2, 3, 4}
+
+	mapped := Map(items, strconv.Itoa)
+	if !reflect.DeepEqual(mapped, []string{"1", "2", "3", "4"}) {
+		t.Fatalf(`Map() = %v, want [1 2 3 4] as strings`, mapped)
+	}
+
+	even := Filter(items, func(i int) bool { return i%2 == 0 })
+	if !reflect.DeepEqual(even, []int{2, 4}) {
+		t.Fatalf(`Filter() = %v, want [2 4]`, even)
+	}
+
+	sum := Reduce(items, 0, func(total int, i int) int { return total + i })
+	if sum != 10 {
+		t.Fatalf(`Reduce() = %d, want 10`, sum)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	unique := Unique([]string{"b", "a", "b", "c", "a"})
+	if !reflect.DeepEqual(unique, []string{"b", "a", "c"}) {
+		t.Fatalf(`Unique() = %v, want [b a c]`, unique)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	chunks, err := Chunk([]int{1, 2, 3, 4, 5}, 2)
+	if !reflect.DeepEqual(chunks, [][]int{{1, 2}, {3, 4}, {5}}) || err != nil {
+		t.Fatalf(`Chunk(size 2) = %v, %v, want [[1 2] [3 4] [5]], nil`, chunks, err)
+	}
+
+	if _, err := Chunk([]int{1}, 0); err != ErrInvalidSize {
+		t.Fatalf(`Chunk(size 0) error = %v, want %v`, err, ErrInvalidSize)
+	}
+}
+
+func TestGroupByPartition(t *testing.T) {
+	products := []product{{1, "a"}, {2, "b"}, {3, "a"}}
+
+	groups := GroupBy(products, func(p product) string { return p.Category })
+	if !reflect.DeepEqual(groups["a"], []product{{1, "a"}, {3, "a"}}) || len(groups) != 2 {
+		t.Fatalf(`GroupBy() = %v, want two groups with products 1 and 3 in "a"`, groups)
+	}
+
+	odd, even := Partition(products, func(p product) bool { return p.Id%2 == 1 })
+	if len(odd) != 2 || len(even) != 1 || even[0].Id != 2 {
+		t.Fatalf(`Partition() = %v, %v, want products 1, 3 and 2`, odd, even)
+	}
+}
+
+func TestDiffIntersect(t *testing.T) {
+	a := []string{"GET", "POST", "PUT", "POST"}
+	b := []string{"POST", "DELETE"}
+
+	if diff := Diff(a, b); !reflect.DeepEqual(diff, []string{"GET", "PUT"}) {
+		t.Fatalf(`Diff() = %v, want [GET PUT]`, diff)
+	}
+
+	if intersect := Intersect(a, b); !reflect.DeepEqual(intersect, []string{"POST"}) {
+		t.Fatalf(`Intersect() = %v, want [POST]`, intersect)
+	}
+}
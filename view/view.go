@@ -0,0 +1,279 @@
+// Package view renders html/template pages wrapped in layouts, with partials and helper functions
+// shared by every page.
+//
+// Templates are looked up by their path without the extension:
+//
+//	templates/layouts/main.html    a layout, renders the blocks of the page with {{ block "content" . }}{{ end }}
+//	templates/partials/nav.html    a partial, included with {{ template "partials/nav" . }}
+//	templates/product/show.html    a page, defines the blocks with {{ define "content" }}...{{ end }}
+package view
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/waponix/netgo/logger"
+	"github.com/waponix/netgo/router"
+)
+
+const (
+	DEFAULT_DIR          = "templates"
+	DEFAULT_EXTENSION    = ".html"
+	DEFAULT_ASSET_PREFIX = "/assets"
+	PARTIALS_DIR         = "partials"
+)
+
+type Options struct {
+	// extension of the template files, defaults to DEFAULT_EXTENSION
+	Extension string
+	// layout wrapping every page unless another one is given to Render(), e.g. "layouts/main"
+	Layout string
+	// parse the templates again on every render so that changes show up without a restart
+	Development bool
+	// url prefix of the asset helper, defaults to DEFAULT_ASSET_PREFIX
+	AssetPrefix string
+	// files behind the asset prefix, when given the asset helper adds a hash of the file to bust caches
+	Assets fs.FS
+	// builds the urls of the url helper, defaults to router.Instance()
+	Router URLBuilder
+}
+
+// looks up the path of a named route, implemented by the router
+type URLBuilder interface {
+	URL(name string, pairs ...string) (string, error)
+}
+
+type Engine struct {
+	fsys      fs.FS
+	options   Options
+	funcs     template.FuncMap
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	// hashes of the asset files, by name
+	versions sync.Map
+}
+
+// read by every render, an atomic keeps concurrent first renders and SetInstance() from racing
+var engineInstance atomic.Pointer[Engine]
+
+// Public: returns the engine used by the handlers, loading DEFAULT_DIR unless SetInstance() was called
+func Instance() *Engine {
+	if e := engineInstance.Load(); e != nil {
+		return e
+	}
+
+	engineInstance.CompareAndSwap(nil, New(DEFAULT_DIR, Options{}))
+	return engineInstance.Load()
+}
+
+// Public: makes the engine the one returned by Instance()
+func SetInstance(e *Engine) {
+	engineInstance.Store(e)
+}
+
+// Public: creates an engine loading the templates of the directory
+func New(dir string, options Options) *Engine {
+	return NewFS(os.DirFS(dir), options)
+}
+
+// Public: creates an engine loading the templates of a file system like an embed.FS
+func NewFS(fsys fs.FS, options Options) *Engine {
+	if options.Extension == "" {
+		options.Extension = DEFAULT_EXTENSION
+	}
+
+	if options.AssetPrefix == "" {
+		options.AssetPrefix = DEFAULT_ASSET_PREFIX
+	}
+
+	return &Engine{
+		fsys:      fsys,
+		options:   options,
+		funcs:     template.FuncMap{},
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// register helper functions available to every template, they take precedence over the built-in
+// url and asset helpers
+func (e *Engine) Funcs(funcs template.FuncMap) *Engine {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for name, fn := range funcs {
+		e.funcs[name] = fn
+	}
+	// functions are bound when parsing, compiled templates have to be parsed again
+	e.templates = make(map[string]*template.Template)
+
+	return e
+}
+
+// renders the page into w wrapped in the default layout, or in the given layout ("" for none)
+func (e *Engine) Render(w io.Writer, name string, data any, layout ...string) error {
+	wrapper := e.options.Layout
+	if len(layout) > 0 {
+		wrapper = layout[0]
+	}
+
+	t, err := e.lookup(wrapper, name)
+	if err != nil {
+		return err
+	}
+
+	entry := name
+	if wrapper != "" {
+		entry = wrapper
+	}
+
+	// render into a buffer first so that a failing template never leaves half a page behind
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, entry, data); err != nil {
+		return fmt.Errorf("view: rendering %q: %w", name, err)
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// renders the page as the response, a failing template is logged and answered with 500
+func (e *Engine) HTML(w http.ResponseWriter, r *http.Request, status int, name string, data any, layout ...string) {
+	var buf bytes.Buffer
+	if err := e.Render(&buf, name, data, layout...); err != nil {
+		logger.FromRequest(r).Error(err.Error())
+		router.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// returns the compiled page, from the cache unless in development
+func (e *Engine) lookup(layout string, name string) (*template.Template, error) {
+	key := layout + "|" + name
+
+	if !e.options.Development {
+		e.mu.RLock()
+		t, ok := e.templates[key]
+		e.mu.RUnlock()
+
+		if ok {
+			return t, nil
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t, err := e.compile(layout, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.options.Development {
+		e.templates[key] = t
+	}
+
+	return t, nil
+}
+
+// parses the partials, then the layout and the page last so that its blocks replace the ones of the layout
+func (e *Engine) compile(layout string, name string) (*template.Template, error) {
+	t := template.New("").Funcs(e.helpers()).Funcs(e.funcs)
+
+	err := fs.WalkDir(e.fsys, PARTIALS_DIR, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(file) != e.options.Extension {
+			return err
+		}
+		return e.parse(t, strings.TrimSuffix(file, e.options.Extension))
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if layout != "" {
+		if err := e.parse(t, layout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.parse(t, name); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (e *Engine) parse(t *template.Template, name string) error {
+	source, err := fs.ReadFile(e.fsys, name+e.options.Extension)
+	if err != nil {
+		return fmt.Errorf("view: loading %q: %w", name, err)
+	}
+
+	if _, err := t.New(name).Parse(string(source)); err != nil {
+		return fmt.Errorf("view: parsing %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// the built-in helpers:
+//
+//	{{ url "product.show" "productId" .ID }}  the path of a named route
+//	{{ asset "css/app.css" }}                 the url of an asset
+func (e *Engine) helpers() template.FuncMap {
+	return template.FuncMap{
+		"url":   e.url,
+		"asset": e.asset,
+	}
+}
+
+func (e *Engine) url(name string, pairs ...any) (string, error) {
+	values := make([]string, len(pairs))
+	for i, pair := range pairs {
+		values[i] = fmt.Sprint(pair)
+	}
+
+	builder := e.options.Router
+	if builder == nil {
+		builder = router.Instance()
+	}
+
+	return builder.URL(name, values...)
+}
+
+func (e *Engine) asset(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	url := strings.TrimSuffix(e.options.AssetPrefix, "/") + "/" + name
+
+	if e.options.Assets == nil {
+		return url
+	}
+
+	if version, ok := e.versions.Load(name); ok && !e.options.Development {
+		return url + "?v=" + version.(string)
+	}
+
+	content, err := fs.ReadFile(e.options.Assets, name)
+	if err != nil {
+		return url
+	}
+
+	version := fmt.Sprintf("%x", sha256.Sum256(content))[:8]
+	e.versions.Store(name, version)
+
+	return url + "?v=" + version
+}
@@ -0,0 +1,99 @@
+package view
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type fakeRouter map[string]string
+
+func (f fakeRouter) URL(name string, pairs ...string) (string, error) {
+	path, ok := f[name]
+	if !ok {
+		return "", errors.New("no route named " + name)
+	}
+	return path + "/" + strings.Join(pairs, "/"), nil
+}
+
+func templates() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/main.html":   {Data: []byte(`<title>{{ block "title" . }}netgo{{ end }}</title>{{ template "partials/nav" . }}<main>{{ block "content" . }}{{ end }}</main>`)},
+		"partials/nav.html":   {Data: []byte(`<nav><a href="{{ url "product.show" "productId" 1 }}">first</a></nav>`)},
+		"product/show.html":   {Data: []byte(`{{ define "title" }}{{ .Name }}{{ end }}{{ define "content" }}<h1>{{ .Name }}</h1><link href="{{ asset "css/app.css" }}">{{ end }}`)},
+		"product/plain.html":  {Data: []byte(`<p>{{ shout .Name }}</p>`)},
+		"product/broken.html": {Data: []byte(`{{ define "content" }}{{ .Name.Field }}{{ end }}`)},
+	}
+}
+
+func TestRenderShouldWrapPagesInTheLayout(t *testing.T) {
+	e := NewFS(templates(), Options{
+		Layout: "layouts/main",
+		Router: fakeRouter{"product.show": "/api/product"},
+		Assets: fstest.MapFS{"css/app.css": {Data: []byte("body{}")}},
+	})
+
+	var out strings.Builder
+	if err := e.Render(&out, "product/show", map[string]string{"Name": "<Chair>"}); err != nil {
+		t.Fatalf(`Render("product/show") error = %v, want nil`, err)
+	}
+
+	want := `<title>&lt;Chair&gt;</title><nav><a href="/api/product/productId/1">first</a></nav><main><h1>&lt;Chair&gt;</h1><link href="/assets/css/app.css?v=7c98040a"></main>`
+	if out.String() != want {
+		t.Fatalf(`Render("product/show") = %q, want %q`, out.String(), want)
+	}
+}
+
+func TestRenderWithoutLayoutShouldUseRegisteredFuncs(t *testing.T) {
+	e := NewFS(templates(), Options{Layout: "layouts/main"}).Funcs(template.FuncMap{
+		"shout": strings.ToUpper,
+	})
+
+	var out strings.Builder
+	if err := e.Render(&out, "product/plain", map[string]string{"Name": "chair"}, ""); err != nil || out.String() != "<p>CHAIR</p>" {
+		t.Fatalf(`Render("product/plain") = %q, %v, want "<p>CHAIR</p>"`, out.String(), err)
+	}
+}
+
+func TestDevelopmentShouldReloadTemplates(t *testing.T) {
+	fsys := fstest.MapFS{"page.html": {Data: []byte("v1")}}
+
+	for _, c := range []struct {
+		development bool
+		want        string
+	}{{false, "v1"}, {true, "v2"}} {
+		fsys["page.html"] = &fstest.MapFile{Data: []byte("v1")}
+		e := NewFS(fsys, Options{Development: c.development})
+
+		var out strings.Builder
+		e.Render(&out, "page", nil)
+
+		fsys["page.html"] = &fstest.MapFile{Data: []byte("v2")}
+		out.Reset()
+		e.Render(&out, "page", nil)
+
+		if out.String() != c.want {
+			t.Fatalf(`second render with Development %v = %q, want %q`, c.development, out.String(), c.want)
+		}
+	}
+}
+
+func TestHTMLShouldAnswerWith500WhenRenderingFails(t *testing.T) {
+	e := NewFS(templates(), Options{Layout: "layouts/main", Router: fakeRouter{"product.show": "/api/product"}})
+
+	rec := httptest.NewRecorder()
+	e.HTML(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "product/broken", map[string]string{"Name": "chair"})
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "<nav>") {
+		t.Fatalf(`HTML("product/broken") = %d %q, want 500 without the partial page`, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.HTML(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusCreated, "product/show", map[string]string{"Name": "chair"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf(`HTML("product/show") = %d %q, want 201 text/html`, rec.Code, rec.Header().Get("Content-Type"))
+	}
+}